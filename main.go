@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/julnicolas/httpmon/pkg/app"
@@ -32,8 +35,12 @@ func main() {
 		exitErr(err)
 	}
 
+	// Stop gracefully on SIGTERM, as sent by kubernetes, or on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
 	defer app.Close()
-	if err := app.Run(); err != nil {
+	if err := app.Run(ctx); err != nil {
 		exitErr(err)
 	}
 }
@@ -0,0 +1,93 @@
+package alert
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/metrics"
+)
+
+// AbsentInput configures an Absent alert
+type AbsentInput struct {
+	Name NameT
+	// Section or Host restricts the alert to a section or a host,
+	// all traffic if both are empty
+	Section string
+	Host    string
+	// After is how long without any trace before the rule is true
+	After time.Duration
+	// For is how long the rule must be true before the alert is active
+	For time.Duration
+}
+
+// Absent is a dead man's switch, an alert on traffic which stopped,
+// computed from the LastSeen metric
+type Absent struct {
+	MetricsTimeAlert
+	in AbsentInput
+}
+
+// NewAbsent creates an absent traffic alert
+func NewAbsent(in AbsentInput) *Absent {
+	return &Absent{
+		MetricsTimeAlert: *NewMetricsTimeAlert(in.For, in.check),
+		in:               in,
+	}
+}
+
+// Name returns the alert's name
+func (o *Absent) Name() NameT {
+	return o.in.Name
+}
+
+// Input returns the alert's configuration
+func (o *Absent) Input() AbsentInput {
+	return o.in
+}
+
+// Description returns a human readable description of the alert
+func (o *Absent) Description() string {
+	traffic := "all traffic"
+	switch {
+	case o.in.Section != "":
+		traffic = "section " + o.in.Section
+	case o.in.Host != "":
+		traffic = "host " + o.in.Host
+	}
+	return fmt.Sprintf("active if %s had no request for %s, pending for %s", traffic, o.in.After, o.period)
+}
+
+// Labels returns the alert's name and section or host if any
+func (o *Absent) Labels() Labels {
+	l := Labels{AlertName: string(o.in.Name)}
+	if o.in.Section != "" {
+		l["section"] = o.in.Section
+	}
+	if o.in.Host != "" {
+		l["host"] = o.in.Host
+	}
+	return l
+}
+
+func (o *Absent) DeepCopy() Alert {
+	n := new(Absent)
+	base := o.MetricsTimeAlert.DeepCopy()
+	n.MetricsTimeAlert = *base.(*MetricsTimeAlert)
+	n.in = o.in
+
+	return n
+}
+
+// check is the alert's rule, true if no trace was seen for After
+func (o AbsentInput) check(m metrics.Metric) bool {
+	v := m.(metrics.TimeVector) // routed by metric name
+	return v.Since(o.Section, o.Host) >= o.After
+}
+
+// CheckAbsentTraffic returns an error if both section and host are set
+func CheckAbsentTraffic(section, host string) error {
+	if section != "" && host != "" {
+		return fmt.Errorf("expecting either a section or a host, received %q and %q", section, host)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbsent(t *testing.T) {
+	p := metrics.NewLastSeen()
+	p.Advance(time.Unix(100, 0))
+	p.Update(req(110, "a", "/api", 200))
+	p.Update(req(150, "b", "/help", 200))
+	p.Advance(time.Unix(170, 0))
+	m := p.DeepCopy()
+
+	tests := []struct {
+		in   AbsentInput
+		want bool
+	}{
+		{AbsentInput{After: 20 * time.Second}, true},
+		{AbsentInput{After: 21 * time.Second}, false},
+		{AbsentInput{Section: "/api", After: time.Minute}, true},
+		{AbsentInput{Host: "b", After: time.Minute}, false},
+		// Never seen since the first evaluation
+		{AbsentInput{Section: "/report", After: time.Minute}, true},
+		{AbsentInput{Host: "c", After: 71 * time.Second}, false},
+	}
+
+	for i, test := range tests {
+		assert.Equal(t, test.want, test.in.check(m), i)
+	}
+}
+
+func TestAbsentFiresWhileTrafficIsSilent(t *testing.T) {
+	p := metrics.NewLastSeen()
+	o := NewAbsent(AbsentInput{Name: "dead", After: 30 * time.Second, For: 10 * time.Second})
+
+	p.Update(req(100, "a", "/", 200))
+	states := []State{}
+	for unix := int64(100); unix <= 150; unix += 10 {
+		p.Advance(time.Unix(unix, 0))
+		states = append(states, o.Eval(p.DeepCopy()))
+	}
+	assert.Equal(t, []State{Inactive, Inactive, Inactive, Pending, Pending, Active}, states)
+
+	p.Update(req(155, "a", "/", 200))
+	p.Advance(time.Unix(160, 0))
+	assert.Equal(t, Inactive, o.Eval(p.DeepCopy()))
+}
@@ -2,6 +2,7 @@ package alert
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/julnicolas/httpmon/pkg/metrics"
@@ -24,6 +25,9 @@ type Alert interface {
 	// Description is a human readable text describing
 	// the alert.
 	Description() string
+	// Labels identify the alert in silences and inhibitions,
+	// alertname is the name of the alert's rule
+	Labels() Labels
 	// Creates an entirely new copy, see it as a copy constructor
 	//
 	// TODO: Isolate this into a new Specialised interface
@@ -66,6 +70,30 @@ func (o State) String() string {
 	}
 }
 
+// ParseState parses a state's String, ignoring case
+func ParseState(s string) (State, error) {
+	for _, state := range []State{Inactive, Pending, Active} {
+		if strings.EqualFold(s, state.String()) {
+			return state, nil
+		}
+	}
+	return Inactive, fmt.Errorf("unsupported alert state %q, expecting inactive, pending or active", s)
+}
+
+// MarshalText writes states as their String
+func (o State) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *State) UnmarshalText(b []byte) error {
+	state, err := ParseState(string(b))
+	if err != nil {
+		return err
+	}
+	*o = state
+	return nil
+}
+
 // Others returns the two other possible states
 func (o State) Others() (State, State) {
 	switch o {
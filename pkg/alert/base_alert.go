@@ -10,6 +10,41 @@ import (
 // if true the alert condition if verified, which modifies its state.
 type AlertRule func(metrics.Metric) bool
 
+// Resolution configures how an active alert resolves, the zero value
+// resolves it on the first evaluation its rule is false
+type Resolution struct {
+	// Recover is true when the alert can resolve, when the rule is false
+	// if nil. An alert firing at >= 10 req/s recovering at < 7 req/s
+	// does not flap between 7 and 10.
+	Recover AlertRule
+	// KeepFiringFor is how long Recover must hold before the alert resolves
+	KeepFiringFor time.Duration
+	// MinDuration is the minimum time the alert is active before it resolves
+	MinDuration time.Duration
+}
+
+// ResolveInput configures the Resolution of alerts comparing a value
+// to a threshold
+type ResolveInput struct {
+	// Recovery is the threshold the alert resolves at if HasRecovery,
+	// the alert's threshold otherwise
+	Recovery      float64
+	HasRecovery   bool
+	KeepFiringFor time.Duration
+	MinDuration   time.Duration
+}
+
+// resolution returns the Resolution of an alert whose rule, compared
+// to threshold, is holds
+func (o ResolveInput) resolution(holds func(m metrics.Metric, threshold float64) bool) Resolution {
+	r := Resolution{KeepFiringFor: o.KeepFiringFor, MinDuration: o.MinDuration}
+	if o.HasRecovery {
+		recovery := o.Recovery
+		r.Recover = func(m metrics.Metric) bool { return !holds(m, recovery) }
+	}
+	return r
+}
+
 // BaseAlert defines fields and methods common to all alerts
 // it should be embedded in any deriving struct
 type BaseAlert struct {
@@ -18,17 +53,34 @@ type BaseAlert struct {
 	rule     AlertRule
 	evalTime int64 // evaluation time
 	state    State
+
+	resolution  Resolution
+	newTimer    func(time.Duration) Timer
+	keepTimer   Timer // started when the alert recovers
+	activeTimer Timer // started when the alert becomes active
+	recovering  bool  // true once keepTimer started
 }
 
 // NewBaseAlert creates a new alert
 func NewBaseAlert(period time.Duration, rule AlertRule) *BaseAlert {
+	newTimer := func(d time.Duration) Timer { return NewSystemTimer(d) }
 	return &BaseAlert{
-		timer:  NewSystemTimer(period),
-		period: period,
-		rule:   rule,
+		timer:       newTimer(period),
+		period:      period,
+		rule:        rule,
+		newTimer:    newTimer,
+		keepTimer:   newTimer(0),
+		activeTimer: newTimer(0),
 	}
 }
 
+// Resolve sets how the alert resolves once active
+func (o *BaseAlert) Resolve(r Resolution) {
+	o.resolution = r
+	o.keepTimer = o.newTimer(r.KeepFiringFor)
+	o.activeTimer = o.newTimer(r.MinDuration)
+}
+
 func (o *BaseAlert) EvalTime() time.Time {
 	return time.Unix(o.evalTime, 0)
 }
@@ -40,17 +92,23 @@ func (o *BaseAlert) State() State {
 
 // Eval runs the alert rule then returns its current state
 func (o *BaseAlert) Eval(m metrics.Metric) State {
-	if o.rule(m) {
+	switch {
+	case o.state == Active:
+		if o.resolved(m) {
+			o.state = Inactive
+		}
+	case o.rule(m):
 		switch o.state {
 		case Inactive:
 			o.timer.Start()
 			o.state = Pending
 		case Pending:
 			if o.timer.Over() {
+				o.activeTimer.Start()
 				o.state = Active
 			}
 		}
-	} else {
+	default:
 		o.state = Inactive
 	}
 
@@ -58,6 +116,36 @@ func (o *BaseAlert) Eval(m metrics.Metric) State {
 	return o.state
 }
 
+// resolved returns true if an active alert can resolve
+func (o *BaseAlert) resolved(m metrics.Metric) bool {
+	var recovered bool
+	if o.resolution.Recover != nil {
+		recovered = o.resolution.Recover(m)
+	} else {
+		recovered = !o.rule(m)
+	}
+	if !recovered {
+		o.recovering = false
+		return false
+	}
+
+	if o.resolution.KeepFiringFor > 0 {
+		if !o.recovering {
+			o.keepTimer.Start()
+			o.recovering = true
+		}
+		if !o.keepTimer.Over() {
+			return false
+		}
+	}
+	if o.resolution.MinDuration > 0 && !o.activeTimer.Over() {
+		return false
+	}
+
+	o.recovering = false
+	return true
+}
+
 func (o *BaseAlert) Description() string {
 	return "BaseAlert"
 }
@@ -66,6 +154,10 @@ func (o *BaseAlert) Name() NameT {
 	return "BaseAlert"
 }
 
+func (o *BaseAlert) Labels() Labels {
+	return Labels{AlertName: string(o.Name())}
+}
+
 func (o *BaseAlert) DeepCopy() Alert {
 	n := new(BaseAlert)
 	*n = *o
@@ -56,6 +56,55 @@ func TestEvalReturnsActiveIfRuleIsTrueAndTimeCOnditionsAreMet(t *testing.T) {
 	assert.Equal(t, Active, state2)
 }
 
+// active returns an active alert whose rule is value >= 10, its timers are not over
+func active(value *float64, r Resolution) *BaseAlert {
+	a := NewBaseAlert(time.Second, func(metrics.Metric) bool { return *value >= 10 })
+	a.Resolve(r)
+	a.timer, a.keepTimer, a.activeTimer = TrueTimer{}, FalseTimer{}, FalseTimer{}
+
+	*value = 10
+	a.Eval(metrics.Counter{}) // pending
+	a.Eval(metrics.Counter{}) // active
+	return a
+}
+
+func TestEvalResolvesAtRecoveryThreshold(t *testing.T) {
+	value := 0.0
+	a := active(&value, Resolution{Recover: func(metrics.Metric) bool { return value < 7 }})
+
+	value = 8 // rule is false but not recovered yet
+	assert.Equal(t, Active, a.Eval(metrics.Counter{}))
+	value = 6
+	assert.Equal(t, Inactive, a.Eval(metrics.Counter{}))
+	value = 8 // fires at 10 again
+	assert.Equal(t, Inactive, a.Eval(metrics.Counter{}))
+}
+
+func TestEvalKeepsFiring(t *testing.T) {
+	value := 0.0
+	a := active(&value, Resolution{KeepFiringFor: time.Minute})
+
+	value = 0
+	assert.Equal(t, Active, a.Eval(metrics.Counter{}))
+	value = 10 // firing again resets the keep timer
+	assert.Equal(t, Active, a.Eval(metrics.Counter{}))
+	assert.False(t, a.recovering)
+	value = 0
+	assert.Equal(t, Active, a.Eval(metrics.Counter{}))
+	a.keepTimer = TrueTimer{}
+	assert.Equal(t, Inactive, a.Eval(metrics.Counter{}))
+}
+
+func TestEvalResolvesAfterMinDuration(t *testing.T) {
+	value := 0.0
+	a := active(&value, Resolution{MinDuration: time.Minute})
+
+	value = 0
+	assert.Equal(t, Active, a.Eval(metrics.Counter{}))
+	a.activeTimer = TrueTimer{}
+	assert.Equal(t, Inactive, a.Eval(metrics.Counter{}))
+}
+
 /*
 
 To the reviewers: WIP on a more complex testing scenario
@@ -0,0 +1,137 @@
+package alert
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/metrics"
+)
+
+// ErrorRatioInput configures an ErrorRatio alert
+type ErrorRatioInput struct {
+	Name NameT
+	// Classes are the status classes counted as errors, e.g. 5xx
+	Classes []string
+	// Section restricts the ratio to a section, all sections if empty
+	Section string
+	// Window is the time over which the ratio is computed,
+	// rounded up to a number of periods
+	Window time.Duration
+	// Threshold is the ratio in [0, 1] over which the alert is on
+	Threshold float64
+	// MinRequests is the number of requests in Window below which
+	// the ratio is not significant, the rule is false then
+	MinRequests uint
+	// For is how long the ratio must be over threshold before the alert is active
+	For     time.Duration
+	Resolve ResolveInput
+}
+
+// ErrorRatio is an alert on the ratio of requests of some status classes
+// to all requests, computed from the StatusClasses metric
+type ErrorRatio struct {
+	MetricsTimeAlert
+	in ErrorRatioInput
+}
+
+// NewErrorRatio creates an error ratio alert, in's classes must have
+// been checked with CheckClasses
+func NewErrorRatio(in ErrorRatioInput) *ErrorRatio {
+	o := &ErrorRatio{
+		MetricsTimeAlert: *NewMetricsTimeAlert(in.For,
+			func(m metrics.Metric) bool { return in.check(m, in.Threshold) }),
+		in: in,
+	}
+	o.Resolve(in.Resolve.resolution(in.check))
+
+	return o
+}
+
+// Name returns the alert's name
+func (o *ErrorRatio) Name() NameT {
+	return o.in.Name
+}
+
+// Input returns the alert's configuration
+func (o *ErrorRatio) Input() ErrorRatioInput {
+	return o.in
+}
+
+// Description returns a human readable description of the alert
+func (o *ErrorRatio) Description() string {
+	section := ""
+	if o.in.Section != "" {
+		section = " of " + o.in.Section
+	}
+	return fmt.Sprintf("active if %s ratio%s >= %g%% over %s (%d requests min) for %s",
+		strings.Join(o.in.Classes, "+"), section, o.in.Threshold*100, o.in.Window, o.in.MinRequests, o.period)
+}
+
+// Labels returns the alert's name and section if any
+func (o *ErrorRatio) Labels() Labels {
+	l := Labels{AlertName: string(o.in.Name)}
+	if o.in.Section != "" {
+		l["section"] = o.in.Section
+	}
+	return l
+}
+
+func (o *ErrorRatio) DeepCopy() Alert {
+	n := new(ErrorRatio)
+	base := o.MetricsTimeAlert.DeepCopy()
+	n.MetricsTimeAlert = *base.(*MetricsTimeAlert)
+	n.in = o.in
+
+	return n
+}
+
+// check is the alert's rule, true if the ratio is at least threshold
+func (o ErrorRatioInput) check(m metrics.Metric, threshold float64) bool {
+	v := m.(metrics.ClassVector) // routed by metric name
+	classes := v.Total()
+	if o.Section != "" {
+		classes = v.TypedLabels()[o.Section]
+	}
+
+	// Rates are turned back into counts, whole numbers
+	seconds := v.Period().Seconds()
+	points := int(math.Ceil(o.Window.Seconds() / seconds))
+	errors, all := 0.0, 0.0
+	for class, series := range classes {
+		series = series[max(len(series)-points, 0):]
+		n := 0.0
+		for _, rate := range series {
+			n += math.Round(rate * seconds)
+		}
+
+		all += n
+		if slices.Contains(o.Classes, class) {
+			errors += n
+		}
+	}
+
+	if all == 0 || all < float64(o.MinRequests) {
+		return false
+	}
+	return errors/all >= threshold
+}
+
+var classPattern = regexp.MustCompile(`^[1-5]xx$`)
+
+// CheckClasses returns an error if classes is empty or if a class is not
+// a status class such as 5xx
+func CheckClasses(classes []string) error {
+	if len(classes) == 0 {
+		return fmt.Errorf("at least one status class is needed")
+	}
+	for _, c := range classes {
+		if !classPattern.MatchString(c) {
+			return fmt.Errorf("status classes are 1xx to 5xx, received %q", c)
+		}
+	}
+	return nil
+}
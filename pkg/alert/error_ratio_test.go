@@ -0,0 +1,42 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorRatio(t *testing.T) {
+	p := metrics.NewStatusClasses(10*time.Second, 100)
+	// Periods: 1 error out of 2 requests, 0 out of 10 then 1 out of 10
+	p.Update(req(100, "a", "/api", 200))
+	p.Update(req(101, "a", "/api", 503))
+	for unix := int64(110); unix < 129; unix++ {
+		p.Update(req(unix, "a", "/help", 200))
+	}
+	p.Update(req(129, "a", "/api", 500))
+	p.Update(req(130, "a", "/api", 200)) // closes the last period
+	m := p.DeepCopy()
+
+	in := ErrorRatioInput{Classes: []string{"5xx"}, Window: 10 * time.Second, Threshold: 0.05, MinRequests: 10}
+	tests := []struct {
+		update func(*ErrorRatioInput)
+		want   bool
+	}{
+		{func(in *ErrorRatioInput) {}, true},
+		{func(in *ErrorRatioInput) { in.Threshold, in.Window = 0.06, 20*time.Second }, false},
+		{func(in *ErrorRatioInput) { in.Threshold, in.Window = 0.09, 25*time.Second }, true},
+		// Too few /api requests
+		{func(in *ErrorRatioInput) { in.Section, in.Window = "/api", 30*time.Second }, false},
+		{func(in *ErrorRatioInput) { in.Section, in.MinRequests = "/api", 0 }, true},
+		{func(in *ErrorRatioInput) { in.Classes = []string{"4xx"} }, false},
+	}
+
+	for i, test := range tests {
+		in := in
+		test.update(&in)
+		assert.Equal(t, test.want, in.check(m, in.Threshold), i)
+	}
+}
@@ -0,0 +1,143 @@
+package alert
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julnicolas/httpmon/pkg/metrics"
+)
+
+// FanOut evaluates a rule once per value of its By label, e.g. once
+// per section. Each instance is an independent alert with its own timer,
+// named after the rule and its label value: API errors{section="/api"}.
+//
+// Instances are created as label values appear in the metric, their
+// number is bounded by the metric's cardinality.
+// A rule without By has a single instance named after the rule.
+type FanOut struct {
+	in        RuleInput
+	instances map[string]*Rule // keys are label values
+}
+
+func NewFanOut(in RuleInput) *FanOut {
+	return &FanOut{
+		in:        in,
+		instances: make(map[string]*Rule),
+	}
+}
+
+// Name returns the rule's name
+func (o *FanOut) Name() NameT {
+	return o.in.Name
+}
+
+// Input returns the rule's configuration
+func (o *FanOut) Input() RuleInput {
+	return o.in
+}
+
+// Eval evaluates every instance against m, creating instances for new
+// label values, and returns them sorted by label value
+func (o *FanOut) Eval(m metrics.Metric) []Alert {
+	values := []string{""}
+	if o.in.By != "" {
+		values = labelValues(m, o.in.By)
+	}
+
+	alerts := make([]Alert, 0, len(values))
+	for _, v := range values {
+		r, ok := o.instances[v]
+		if !ok {
+			r = NewRule(o.instance(v))
+			o.instances[v] = r
+		}
+		r.Eval(m)
+		alerts = append(alerts, r)
+	}
+
+	return alerts
+}
+
+// instance returns the configuration of the instance of label value v
+func (o *FanOut) instance(v string) RuleInput {
+	in := o.in
+	if in.By == "" {
+		return in
+	}
+
+	in.Name = NameT(fmt.Sprintf("%s{%s=%q}", o.in.Name, o.in.By, v))
+	in.rule = o.in.Name
+	in.Selector = maps.Clone(o.in.Selector)
+	if in.Selector == nil {
+		in.Selector = make(map[string]string, 1)
+	}
+	in.Selector[in.By] = v
+	in.By = ""
+
+	return in
+}
+
+// CheckBy returns an error if rules on metric can't fan out over label by
+func CheckBy(metric, by string, selector map[string]string) error {
+	if by == "" {
+		return nil
+	}
+	if by == "quantile" {
+		return fmt.Errorf("can't fan out over quantiles, select one instead")
+	}
+	if _, ok := selector[by]; ok {
+		return fmt.Errorf("%s is already selected", by)
+	}
+	if !hasLabel(metric, by) {
+		return fmt.Errorf("%s has no label %q, expecting one of %s", metric, by, strings.Join(selectors[metric], ", "))
+	}
+	return nil
+}
+
+// labelValues returns the sorted values of label in m
+func labelValues(m metrics.Metric, label string) []string {
+	values := map[string]bool{}
+
+	switch v := m.(type) {
+	case metrics.CounterVector:
+		for section := range v.TypedLabels() {
+			values[section] = true
+		}
+	case metrics.Counter:
+		for host := range v.TypedLabels() {
+			values[host] = true
+		}
+	case metrics.RoutePerStatusCounter:
+		for code, sections := range v.TypedLabels() {
+			if label == "status" {
+				values[strconv.FormatUint(uint64(code), 10)] = true
+				continue
+			}
+			for section := range sections {
+				values[section] = true
+			}
+		}
+	case metrics.QuantileVector:
+		for section := range v.TypedLabels() {
+			values[section] = true
+		}
+	case metrics.Distribution:
+		for section := range v.TypedLabels() {
+			values[section] = true
+		}
+	default:
+		// Should never happen, metrics are checked with configuration
+		panic(fmt.Errorf("configuration error - metric %s is not supported by rules", m.Name()))
+	}
+
+	sorted := make([]string, 0, len(values))
+	for v := range values {
+		sorted = append(sorted, v)
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
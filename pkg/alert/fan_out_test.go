@@ -0,0 +1,51 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFanOutTracksLabelValuesIndependently(t *testing.T) {
+	o := NewFanOut(RuleInput{
+		Name:        "busy",
+		Metric:      metrics.ReqsPerS,
+		By:          "section",
+		Aggregation: Last,
+		Operator:    GreaterOrEqual,
+		Threshold:   0.2,
+		For:         5 * time.Second,
+	})
+	p := metrics.NewRequestsPerSecond(10*time.Second, 100)
+	states := func() map[NameT]State {
+		s := map[NameT]State{}
+		for _, a := range o.Eval(p.DeepCopy()) {
+			s[a.Name()] = a.State()
+		}
+		return s
+	}
+
+	p.Update(req(100, "a", "/api", 200))
+	p.Update(req(101, "a", "/api", 200))
+	p.Update(req(102, "a", "/help", 200))
+	p.Update(req(110, "a", "/api", 200))
+	p.Update(req(111, "a", "/api", 200))
+	assert.Equal(t, map[NameT]State{`busy{section="/api"}`: Pending, `busy{section="/help"}`: Inactive}, states())
+
+	// A new section gets its own instance, /api keeps its timer
+	p.Update(req(120, "a", "/report", 200))
+	assert.Equal(t, map[NameT]State{
+		`busy{section="/api"}`:    Active,
+		`busy{section="/help"}`:   Inactive,
+		`busy{section="/report"}`: Inactive,
+	}, states())
+}
+
+func TestCheckBy(t *testing.T) {
+	assert.NoError(t, CheckBy(metrics.RoutesPerStatusN, "section", map[string]string{"status": "500"}))
+	assert.Error(t, CheckBy(metrics.RoutesPerStatusN, "status", map[string]string{"status": "500"}))
+	assert.Error(t, CheckBy(metrics.LatencyN, "quantile", nil))
+	assert.Error(t, CheckBy(metrics.ReqsPerS, "host", nil))
+}
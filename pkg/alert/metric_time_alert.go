@@ -14,13 +14,20 @@ func NewMetricsTimeAlert(period time.Duration, rule AlertRule) *MetricsTimeAlert
 	o := &MetricsTimeAlert{
 		BaseAlert: *NewBaseAlert(period, rule),
 	}
-	o.timer = NewMetricsTimer(period)
+	o.newTimer = func(d time.Duration) Timer { return NewMetricsTimer(d) }
+	o.timer = o.newTimer(period)
+	o.keepTimer = o.newTimer(0)
+	o.activeTimer = o.newTimer(0)
 
 	return o
 }
 
 func (o *MetricsTimeAlert) Eval(m metrics.Metric) State {
-	o.timer.(*MetricsTimer).Metric(m)
+	for _, t := range []Timer{o.timer, o.keepTimer, o.activeTimer} {
+		if mt, ok := t.(*MetricsTimer); ok {
+			mt.Metric(m)
+		}
+	}
 	return o.BaseAlert.Eval(m)
 }
 
@@ -32,6 +39,10 @@ func (o *MetricsTimeAlert) Name() NameT {
 	return "MetricsTimeAlert"
 }
 
+func (o *MetricsTimeAlert) Labels() Labels {
+	return Labels{AlertName: string(o.Name())}
+}
+
 func (o *MetricsTimeAlert) DeepCopy() Alert {
 	base := o.BaseAlert.DeepCopy()
 	n := new(MetricsTimeAlert)
@@ -29,14 +29,18 @@ type RequestsPerSecondInput struct {
 	Period time.Duration
 	// This data would be made an interface to generalise alerting
 	Threshold float64 // req/s threshold, if greater trigger alert
+	Resolve   ResolveInput
 }
 
 func NewRequestsPerSecond(in RequestsPerSecondInput) *RequestsPerSecond {
-	return &RequestsPerSecond{
+	o := &RequestsPerSecond{
 		MetricsTimeAlert: *NewMetricsTimeAlert(in.Period,
 			func(m metrics.Metric) bool { return checkRate(m, in.Threshold) }),
 		threshold: in.Threshold,
 	}
+	o.Resolve(in.Resolve.resolution(checkRate))
+
+	return o
 }
 
 // Name returns the alert's name
@@ -52,6 +56,11 @@ func (o *RequestsPerSecond) Description() string {
 		o.period)
 }
 
+// Labels returns the alert's name
+func (o *RequestsPerSecond) Labels() Labels {
+	return Labels{AlertName: string(ReqPerS)}
+}
+
 func (o *RequestsPerSecond) DeepCopy() Alert {
 	n := new(RequestsPerSecond)
 	base := o.MetricsTimeAlert.DeepCopy()
@@ -0,0 +1,370 @@
+package alert
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/metrics"
+)
+
+// Aggregation reduces the last points of a series to a single value
+type Aggregation string
+
+const (
+	// Last is the last point
+	Last Aggregation = "last"
+	// Avg is the mean of the last points
+	Avg Aggregation = "avg"
+	// Sum is the sum of the last points
+	Sum Aggregation = "sum"
+)
+
+// Operator compares an aggregated value to a rule's threshold
+type Operator string
+
+const (
+	Greater        Operator = ">"
+	GreaterOrEqual Operator = ">="
+	Less           Operator = "<"
+	LessOrEqual    Operator = "<="
+	Equal          Operator = "=="
+	NotEqual       Operator = "!="
+)
+
+// selectors lists the labels a rule can select, per metric name
+var selectors = map[string][]string{
+	metrics.ReqsPerS:         {"section"},
+	metrics.ReqsPerHost:      {"host"},
+	metrics.RoutesPerStatusN: {"section", "status"},
+	metrics.LatencyN:         {"section", "quantile"},
+	metrics.BytesN:           {"section", "quantile"},
+}
+
+// RuleInput configures a Rule
+type RuleInput struct {
+	Name   NameT
+	Metric string // metric name, see selectors for supported ones
+	// Selector selects a labelled series of the metric,
+	// the metric's total is used when empty
+	Selector map[string]string
+	// By is a label the rule fans out over, see FanOut
+	By          string
+	Aggregation Aggregation
+	Points      int // number of last points aggregated by Avg and Sum
+	Operator    Operator
+	Threshold   float64
+	// For is how long the condition must hold before the alert is active
+	For     time.Duration
+	Resolve ResolveInput
+	rule    NameT // name of the fanned out rule, set on instances
+}
+
+// Rule is an alert declared in configuration. It compares a metric's
+// series, aggregated over its last points, to a threshold.
+type Rule struct {
+	MetricsTimeAlert
+	in RuleInput
+}
+
+// NewRule creates a rule alert, in must have been checked
+// with the Check functions
+func NewRule(in RuleInput) *Rule {
+	o := &Rule{
+		MetricsTimeAlert: *NewMetricsTimeAlert(in.For,
+			func(m metrics.Metric) bool { return in.check(m, in.Threshold) }),
+		in: in,
+	}
+	o.Resolve(in.Resolve.resolution(in.check))
+
+	return o
+}
+
+// Name returns the alert's name
+func (o *Rule) Name() NameT {
+	return o.in.Name
+}
+
+// Description returns a human readable description of the alert
+func (o *Rule) Description() string {
+	return fmt.Sprintf("active if %s %s %g for %s", o.in, o.in.Operator, o.in.Threshold, o.period)
+}
+
+// Labels returns the rule's name and selected labels, fan-out instances
+// are named after their rule
+func (o *Rule) Labels() Labels {
+	l := make(Labels, len(o.in.Selector)+1)
+	for k, v := range o.in.Selector {
+		l[k] = v
+	}
+	l[AlertName] = string(o.in.Name)
+	if o.in.rule != "" {
+		l[AlertName] = string(o.in.rule)
+	}
+	return l
+}
+
+func (o *Rule) DeepCopy() Alert {
+	n := new(Rule)
+	base := o.MetricsTimeAlert.DeepCopy()
+	n.MetricsTimeAlert = *base.(*MetricsTimeAlert)
+	n.in = o.in
+
+	return n
+}
+
+// String returns the aggregated series, e.g. avg(ReqsPerSecond{section="/api"}[5])
+func (o RuleInput) String() string {
+	keys := make([]string, 0, len(o.Selector))
+	for k := range o.Selector {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	labels := make([]string, 0, len(keys))
+	for _, k := range keys {
+		labels = append(labels, fmt.Sprintf("%s=%q", k, o.Selector[k]))
+	}
+
+	s := o.Metric
+	if len(labels) > 0 {
+		s += "{" + strings.Join(labels, ",") + "}"
+	}
+	if o.Aggregation == Last {
+		return s
+	}
+	return fmt.Sprintf("%s(%s[%d])", o.Aggregation, s, o.Points)
+}
+
+// check is the rule's predicate, false when there is no value
+func (o RuleInput) check(m metrics.Metric, threshold float64) bool {
+	series := selectSeries(m, o.Selector)
+	if len(series) == 0 {
+		return false
+	}
+
+	return o.Operator.compare(o.Aggregation.reduce(series, o.Points), threshold)
+}
+
+// CheckMetric returns an error if rules can't be evaluated against metric
+func CheckMetric(metric string) error {
+	if _, ok := selectors[metric]; !ok {
+		names := make([]string, 0, len(selectors))
+		for name := range selectors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unsupported metric %q, expecting one of %s", metric, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// CheckSelector returns an error if selector has a label metric doesn't have
+// or an invalid label value
+func CheckSelector(metric string, selector map[string]string) error {
+	for label, value := range selector {
+		if !hasLabel(metric, label) {
+			return fmt.Errorf("%s has no label %q, expecting one of %s", metric, label, strings.Join(selectors[metric], ", "))
+		}
+
+		switch label {
+		case "status":
+			if _, err := strconv.ParseUint(value, 10, 0); err != nil {
+				return fmt.Errorf("status must be a status code, received %q", value)
+			}
+		case "quantile":
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil || q < 0 || q > 1 {
+				return fmt.Errorf("quantile must be in [0, 1], received %q", value)
+			}
+			if metric == metrics.LatencyN && quantileIndex(metrics.LatencyQuantiles, q) < 0 {
+				return fmt.Errorf("latency quantile must be one of %v, received %q", metrics.LatencyQuantiles, value)
+			}
+		}
+	}
+	return nil
+}
+
+// hasLabel returns true if rules can select label of metric
+func hasLabel(metric, label string) bool {
+	for _, l := range selectors[metric] {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAggregation returns an error if a is not supported
+// or if points is not a valid number of points for it
+func CheckAggregation(a Aggregation, points int) error {
+	switch a {
+	case Last:
+		return nil
+	case Avg, Sum:
+		if points < 1 {
+			return fmt.Errorf("%s needs at least 1 point, received %d", a, points)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported aggregation %q, expecting last, avg or sum", a)
+	}
+}
+
+// CheckOperator returns an error if op is not supported
+func CheckOperator(op Operator) error {
+	switch op {
+	case Greater, GreaterOrEqual, Less, LessOrEqual, Equal, NotEqual:
+		return nil
+	default:
+		return fmt.Errorf("unsupported operator %q, expecting >, >=, <, <=, == or !=", op)
+	}
+}
+
+func (o Aggregation) reduce(series []float64, points int) float64 {
+	switch o {
+	case Last:
+		return series[len(series)-1]
+	case Avg, Sum:
+		if points < len(series) {
+			series = series[len(series)-points:]
+		}
+		sum := 0.0
+		for _, v := range series {
+			sum += v
+		}
+		if o == Avg {
+			return sum / float64(len(series))
+		}
+		return sum
+	default:
+		// Should never happen, aggregations are checked with configuration
+		panic(fmt.Errorf("configuration error - unsupported aggregation %q", o))
+	}
+}
+
+func (o Operator) compare(v, threshold float64) bool {
+	switch o {
+	case Greater:
+		return v > threshold
+	case GreaterOrEqual:
+		return v >= threshold
+	case Less:
+		return v < threshold
+	case LessOrEqual:
+		return v <= threshold
+	case Equal:
+		return v == threshold
+	case NotEqual:
+		return v != threshold
+	default:
+		// Should never happen, operators are checked with configuration
+		panic(fmt.Errorf("configuration error - unsupported operator %q", o))
+	}
+}
+
+// selectSeries returns the series of m selected by selector, oldest first.
+// Metrics which are not series, such as counters, return a single point.
+// It returns nil if a selected label has no value yet.
+func selectSeries(m metrics.Metric, selector map[string]string) []float64 {
+	section, bySection := selector["section"]
+
+	switch v := m.(type) {
+	case metrics.CounterVector:
+		if bySection {
+			return v.TypedLabels()[section]
+		}
+		return v.Total()
+
+	case metrics.Counter:
+		if host, ok := selector["host"]; ok {
+			n, ok := v.TypedLabels()[host]
+			if !ok {
+				return nil
+			}
+			return []float64{n}
+		}
+		return []float64{v.Total()}
+
+	case metrics.RoutePerStatusCounter:
+		s, byStatus := selector["status"]
+		status, _ := strconv.ParseUint(s, 10, 0)
+		total, found := 0.0, false
+		for code, sections := range v.TypedLabels() {
+			if byStatus && uint64(code) != status {
+				continue
+			}
+			for s, n := range sections {
+				if bySection && s != section {
+					continue
+				}
+				total += n
+				found = true
+			}
+		}
+		if !found {
+			return nil
+		}
+		return []float64{total}
+
+	case metrics.QuantileVector:
+		q := v.Quantiles()[0] // median
+		if s, ok := selector["quantile"]; ok {
+			q, _ = strconv.ParseFloat(s, 64)
+		}
+		i := quantileIndex(v.Quantiles(), q)
+		if i < 0 {
+			return nil
+		}
+		if bySection {
+			series, ok := v.TypedLabels()[section]
+			if !ok {
+				return nil
+			}
+			return series[i]
+		}
+		return v.Total()[i]
+
+	case metrics.Distribution:
+		q := 0.5 // median
+		if s, ok := selector["quantile"]; ok {
+			q, _ = strconv.ParseFloat(s, 64)
+		}
+		sketches := v.Total()
+		if bySection {
+			var ok bool
+			if sketches, ok = v.TypedLabels()[section]; !ok {
+				return nil
+			}
+		}
+		return quantileSeries(sketches, q)
+
+	default:
+		// Should never happen, metrics are checked with configuration
+		panic(fmt.Errorf("configuration error - metric %s is not supported by rules", m.Name()))
+	}
+}
+
+// quantileSeries returns the q quantile of every period's sketch,
+// 0 for a period without measurement like Latency
+func quantileSeries(sketches []*metrics.Sketch, q float64) []float64 {
+	series := make([]float64, len(sketches))
+	for i, s := range sketches {
+		if s != nil {
+			series[i] = s.Quantile(q)
+		}
+	}
+	return series
+}
+
+// quantileIndex returns the index of q in quantiles, -1 if not found
+func quantileIndex(quantiles []float64, q float64) int {
+	for i, v := range quantiles {
+		if v == q {
+			return i
+		}
+	}
+	return -1
+}
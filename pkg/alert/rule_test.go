@@ -0,0 +1,105 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func req(unix int64, host, section string, status uint) trace.Trace {
+	return trace.Trace{Date: time.Unix(unix, 0), RemoteHost: host, Section: section, Status: status}
+}
+
+func TestRuleAggregatesSelectedSeries(t *testing.T) {
+	p := metrics.NewRequestsPerSecond(10*time.Second, 100)
+	// /api rates: 0.2, 0, 0.4 then an ongoing period
+	for _, unix := range []int64{100, 105, 120, 121, 122, 123, 130} {
+		p.Update(req(unix, "a", "/api", 200))
+	}
+	p.Update(req(130, "a", "/help", 200))
+	m := p.DeepCopy()
+
+	tests := []struct {
+		in   RuleInput
+		want bool
+	}{
+		{RuleInput{Aggregation: Last, Operator: GreaterOrEqual, Threshold: 0.4}, true},
+		{RuleInput{Aggregation: Avg, Points: 2, Operator: Equal, Threshold: 0.2}, true},
+		{RuleInput{Aggregation: Avg, Points: 100, Operator: Greater, Threshold: 0.25}, false},
+		{RuleInput{Aggregation: Sum, Points: 3, Operator: Greater, Threshold: 0.55}, true},
+		{RuleInput{Selector: map[string]string{"section": "/api"}, Aggregation: Last, Operator: Less, Threshold: 0.5}, true},
+		// No series yet
+		{RuleInput{Selector: map[string]string{"section": "/none"}, Aggregation: Last, Operator: Less, Threshold: 0.5}, false},
+	}
+
+	for _, test := range tests {
+		test.in.Metric = metrics.ReqsPerS
+		assert.Equal(t, test.want, test.in.check(m, test.in.Threshold), test.in.String())
+	}
+}
+
+func TestRuleSelectsResponseSizePerPeriod(t *testing.T) {
+	p := metrics.NewBytes(10*time.Second, 100)
+	for _, tr := range []trace.Trace{
+		{Date: time.Unix(100, 0), Section: "/api", Bytes: 100},
+		{Date: time.Unix(120, 0), Section: "/help", Bytes: 300},
+		{Date: time.Unix(130, 0), Section: "/api", Bytes: 500},
+	} {
+		p.Update(tr)
+	}
+	m := p.DeepCopy()
+
+	selected := func(selector map[string]string) []float64 { return selectSeries(m, selector) }
+	assert.InDeltaSlice(t, []float64{100, 0, 300}, selected(nil), 3)
+	assert.InDeltaSlice(t, []float64{100, 0, 0}, selected(map[string]string{"section": "/api"}), 3)
+	assert.Nil(t, selected(map[string]string{"section": "/none"}))
+}
+
+func TestRuleSelectsCounters(t *testing.T) {
+	p := metrics.NewRoutePerStatus()
+	p.Update(req(100, "a", "/api", 500))
+	p.Update(req(100, "a", "/api", 500))
+	p.Update(req(100, "a", "/help", 500))
+	p.Update(req(100, "a", "/api", 200))
+	m := p.DeepCopy()
+
+	selected := func(selector map[string]string) []float64 { return selectSeries(m, selector) }
+	assert.Equal(t, []float64{4}, selected(nil))
+	assert.Equal(t, []float64{3}, selected(map[string]string{"status": "500"}))
+	assert.Equal(t, []float64{2}, selected(map[string]string{"status": "500", "section": "/api"}))
+	assert.Nil(t, selected(map[string]string{"status": "404"}))
+}
+
+func TestRuleBecomesActiveAfterFor(t *testing.T) {
+	in := RuleInput{
+		Name:        "api errors",
+		Metric:      metrics.RoutesPerStatusN,
+		Selector:    map[string]string{"status": "500"},
+		Aggregation: Last,
+		Operator:    GreaterOrEqual,
+		Threshold:   1,
+		For:         10 * time.Second,
+	}
+	r := NewRule(in)
+	p := metrics.NewRoutePerStatus()
+
+	p.Update(req(100, "a", "/api", 200))
+	assert.Equal(t, Inactive, r.Eval(p.DeepCopy()))
+	p.Update(req(105, "a", "/api", 500))
+	assert.Equal(t, Pending, r.Eval(p.DeepCopy()))
+	p.Update(req(120, "a", "/api", 200))
+	assert.Equal(t, Active, r.Eval(p.DeepCopy()))
+
+	assert.Equal(t, NameT("api errors"), r.DeepCopy().Name())
+	assert.Equal(t, `active if RoutesPerStatus{status="500"} >= 1 for 10s`, r.Description())
+}
+
+func TestCheckSelector(t *testing.T) {
+	assert.NoError(t, CheckSelector(metrics.LatencyN, map[string]string{"section": "/api", "quantile": "0.99"}))
+	assert.Error(t, CheckSelector(metrics.LatencyN, map[string]string{"quantile": "0.95"}))
+	assert.Error(t, CheckSelector(metrics.ReqsPerS, map[string]string{"host": "a"}))
+	assert.Error(t, CheckSelector(metrics.RoutesPerStatusN, map[string]string{"status": "5xx"}))
+}
@@ -0,0 +1,189 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlertName is the label holding the name of an alert's rule
+const AlertName string = "alertname"
+
+// Where silences are created from, see Silence.CreatedBy
+const (
+	CreatedByConfig string = "config"
+	CreatedByAPI    string = "api"
+	CreatedByUI     string = "ui"
+)
+
+// Labels identify an alert, see Alert.Labels
+type Labels map[string]string
+
+// Matchers returns matchers matching exactly these labels
+func (o Labels) Matchers() map[string]string {
+	m := make(map[string]string, len(o))
+	for k, v := range o {
+		m[k] = regexp.QuoteMeta(v)
+	}
+	return m
+}
+
+// Matchers select alerts by their labels. Values are regular expressions
+// matching whole label values, a missing label has the empty value.
+type Matchers struct {
+	raw map[string]string
+	re  map[string]*regexp.Regexp
+}
+
+// NewMatchers compiles matchers, label names are mapped to regular expressions
+func NewMatchers(m map[string]string) (Matchers, error) {
+	o := Matchers{
+		raw: make(map[string]string, len(m)),
+		re:  make(map[string]*regexp.Regexp, len(m)),
+	}
+	for label, expr := range m {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return Matchers{}, fmt.Errorf("%s - %s", label, err)
+		}
+		o.raw[label] = expr
+		o.re[label] = re
+	}
+	return o, nil
+}
+
+// CheckMatchers returns an error if m is empty or has an invalid expression
+func CheckMatchers(m map[string]string) error {
+	if len(m) == 0 {
+		return fmt.Errorf("at least a matcher is needed")
+	}
+	_, err := NewMatchers(m)
+	return err
+}
+
+// Match returns true if every matcher matches l
+func (o Matchers) Match(l Labels) bool {
+	for label, re := range o.re {
+		if !re.MatchString(l[label]) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns matchers in the Prometheus format, e.g. {alertname=~"API errors"}
+func (o Matchers) String() string {
+	labels := make([]string, 0, len(o.raw))
+	for label := range o.raw {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for i, label := range labels {
+		labels[i] = fmt.Sprintf("%s=~%q", label, o.raw[label])
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// MarshalJSON writes matchers as a map of label names to expressions
+func (o Matchers) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.raw)
+}
+
+// Silence mutes the notifications of the alerts it matches between
+// its start and end times, wall clock
+type Silence struct {
+	ID       string    `json:"id"`
+	Matchers Matchers  `json:"matchers"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Comment  string    `json:"comment"`
+	// CreatedBy is where the silence comes from: config, api or ui
+	CreatedBy string `json:"created_by"`
+}
+
+// NewSilence creates a silence, its ID is set by the alert manager
+func NewSilence(matchers map[string]string, startsAt, endsAt time.Time, comment, createdBy string) (Silence, error) {
+	if err := CheckMatchers(matchers); err != nil {
+		return Silence{}, err
+	}
+	if !endsAt.After(startsAt) {
+		return Silence{}, fmt.Errorf("silences must end after they start, received %s to %s",
+			startsAt.Format(time.RFC3339), endsAt.Format(time.RFC3339))
+	}
+	m, _ := NewMatchers(matchers)
+
+	return Silence{
+		Matchers:  m,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		Comment:   comment,
+		CreatedBy: createdBy,
+	}, nil
+}
+
+// Active returns true if the silence applies at t
+func (o Silence) Active(t time.Time) bool {
+	return !t.Before(o.StartsAt) && t.Before(o.EndsAt)
+}
+
+// Mutes returns true if the silence applies to an alert labelled l at t
+func (o Silence) Mutes(l Labels, t time.Time) bool {
+	return o.Active(t) && o.Matchers.Match(l)
+}
+
+// Inhibition mutes the alerts matched by Target while an alert
+// matched by Source is active, e.g. a global traffic alert inhibits
+// per-section alerts.
+type Inhibition struct {
+	Source Matchers `json:"source"`
+	Target Matchers `json:"target"`
+	// Equal are labels which must have the same value in the source
+	// and target alerts
+	Equal []string `json:"equal"`
+}
+
+// NewInhibition creates an inhibition rule, source and target
+// must have been checked with CheckMatchers
+func NewInhibition(source, target map[string]string, equal []string) Inhibition {
+	s, err := NewMatchers(source)
+	if err != nil {
+		// Should never happen, matchers are checked with configuration
+		panic(fmt.Errorf("configuration error - inhibition source %s", err))
+	}
+	t, err := NewMatchers(target)
+	if err != nil {
+		// Should never happen, matchers are checked with configuration
+		panic(fmt.Errorf("configuration error - inhibition target %s", err))
+	}
+	return Inhibition{Source: s, Target: t, Equal: equal}
+}
+
+// Inhibits returns true if an active alert labelled source
+// inhibits an alert labelled target. Alerts never inhibit themselves.
+func (o Inhibition) Inhibits(source, target Labels) bool {
+	if !o.Source.Match(source) || !o.Target.Match(target) {
+		return false
+	}
+	for _, label := range o.Equal {
+		if source[label] != target[label] {
+			return false
+		}
+	}
+	return !equalLabels(source, target)
+}
+
+func equalLabels(a, b Labels) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
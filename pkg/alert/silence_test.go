@@ -0,0 +1,52 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchers(t *testing.T) {
+	m, err := NewMatchers(map[string]string{AlertName: "API errors|busy", "section": ".+"})
+	assert.NoError(t, err)
+
+	assert.True(t, m.Match(Labels{AlertName: "busy", "section": "/api"}))
+	assert.False(t, m.Match(Labels{AlertName: "API errors"}))
+	// Expressions match whole values
+	assert.False(t, m.Match(Labels{AlertName: "busy hosts", "section": "/api"}))
+	assert.Equal(t, `{alertname=~"API errors|busy",section=~".+"}`, m.String())
+
+	// Labels' matchers match these labels only
+	l := Labels{AlertName: `errors{section="/api"}`}
+	exact, err := NewMatchers(l.Matchers())
+	assert.NoError(t, err)
+	assert.True(t, exact.Match(l))
+	assert.False(t, exact.Match(Labels{AlertName: `errors{section="/help"}`}))
+
+	assert.Error(t, CheckMatchers(map[string]string{}))
+	assert.Error(t, CheckMatchers(map[string]string{AlertName: "("}))
+}
+
+func TestSilenceIsActiveFromStartToEnd(t *testing.T) {
+	start := time.Unix(100, 0)
+	s, err := NewSilence(map[string]string{AlertName: "busy"}, start, start.Add(time.Hour), "", CreatedByAPI)
+	assert.NoError(t, err)
+
+	assert.False(t, s.Mutes(Labels{AlertName: "busy"}, start.Add(-time.Second)))
+	assert.True(t, s.Mutes(Labels{AlertName: "busy"}, start))
+	assert.False(t, s.Mutes(Labels{AlertName: "other"}, start))
+	assert.False(t, s.Mutes(Labels{AlertName: "busy"}, start.Add(time.Hour)))
+
+	_, err = NewSilence(map[string]string{AlertName: "busy"}, start, start, "", CreatedByAPI)
+	assert.Error(t, err)
+}
+
+func TestInhibition(t *testing.T) {
+	i := NewInhibition(map[string]string{AlertName: "errors"}, map[string]string{AlertName: "errors"}, []string{"section"})
+
+	assert.True(t, i.Inhibits(Labels{AlertName: "errors", "section": "/api", "status": "500"}, Labels{AlertName: "errors", "section": "/api"}))
+	assert.False(t, i.Inhibits(Labels{AlertName: "errors", "section": "/api"}, Labels{AlertName: "errors", "section": "/help"}))
+	// Alerts don't inhibit themselves
+	assert.False(t, i.Inhibits(Labels{AlertName: "errors", "section": "/api"}, Labels{AlertName: "errors", "section": "/api"}))
+}
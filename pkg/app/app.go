@@ -1,28 +1,74 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/julnicolas/httpmon/pkg/alert"
 	"github.com/julnicolas/httpmon/pkg/backend"
 	"github.com/julnicolas/httpmon/pkg/config"
 	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/julnicolas/httpmon/pkg/notify"
+	"github.com/julnicolas/httpmon/pkg/server"
 	"github.com/julnicolas/httpmon/pkg/ui"
+	"github.com/sirupsen/logrus"
 )
 
 type App struct {
 	backend  *backend.Backend
-	frontend *ui.Renderer
+	frontend *ui.Renderer   // nil if headless
+	server   *server.Server // nil if no listen address is configured
+	notifier *notify.Dispatcher
+	refresh  atomic.Int64 // dashboards' refresh interval, reloadable
+
+	mutex sync.Mutex    // serialises reloads
+	conf  config.Config // effective configuration
 }
 
+// uiSilence is how long alerts are silenced for from the Alerts page
+const uiSilence = time.Hour
+
 func NewApp(c config.Config) *App {
 	back := backend.NewBackend(c)
-	frontend := ui.NewRenderer()
 
-	return &App{
+	var frontend *ui.Renderer
+	if !c.UI.Headless {
+		frontend = ui.NewRenderer()
+	}
+
+	var srv *server.Server
+	if c.Server.Listen != "" {
+		srv = server.NewServer(c, back)
+	}
+
+	notifier := notify.NewDispatcher(c.Notify)
+	if notifier.Enabled() {
+		back.OnAlert(notifier.Notify)
+	}
+
+	o := &App{
 		backend:  back,
 		frontend: frontend,
+		server:   srv,
+		notifier: notifier,
+		conf:     c,
+	}
+	o.refresh.Store(int64(c.UI.Refresh))
+	if srv != nil {
+		srv.OnReload(o.Reload)
+	}
+	if frontend != nil {
+		frontend.OnAlertsKey('s', fmt.Sprintf("silence active and pending alerts for %s", uiSilence), o.silenceAlerts)
+		frontend.OnAlertsKey('u', "remove silences set with s", o.unsilenceAlerts)
 	}
+
+	return o
 }
 
 func (o *App) Init() error {
@@ -30,27 +76,134 @@ func (o *App) Init() error {
 		return err
 	}
 
-	if err := o.frontend.Init(); err != nil {
-		return err
+	if o.server != nil {
+		if err := o.server.Start(); err != nil {
+			return err
+		}
+		logrus.Infof("serving metrics on http://%s/metrics", o.server.Addr())
+	}
+
+	if o.frontend != nil {
+		if err := o.frontend.Init(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (o *App) Run() error {
+// Run runs the app until the UI is quit, ctx is done or
+// the backend fails
+func (o *App) Run(ctx context.Context) error {
+	o.notifier.Start(ctx)
 	go o.backend.Run()
+	go o.reloadOnHangup(ctx)
 
-	for o.frontend.Running() && o.backend.RunErr() == nil {
+	if o.frontend == nil {
+		return o.runHeadless(ctx)
+	}
+
+	for o.frontend.Running() && o.backend.RunErr() == nil && ctx.Err() == nil {
 		o.updateDashboards() // should be moved in view with view/renderer dependecy reversed
 		if err := o.frontend.Render(); err != nil {
 			return err
 		}
 
-		time.Sleep(250 * time.Millisecond)
+		time.Sleep(time.Duration(o.refresh.Load()))
 	}
 	return o.backend.RunErr()
 }
 
+// runHeadless logs alert state transitions until ctx is done or the
+// backend fails. Transitions not consumed are dropped once Alerts() is full.
+func (o *App) runHeadless(ctx context.Context) error {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for o.backend.RunErr() == nil {
+		select {
+		case t := <-o.backend.Alerts():
+			if t.Prev != t.Alert.State() {
+				logrus.Infof("alert %q is %s", t.Alert.Name(), t.Alert.State())
+			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			logrus.Infof("shutting down")
+			return nil
+		}
+	}
+	return o.backend.RunErr()
+}
+
+// reloadOnHangup reloads the configuration on SIGHUP until ctx is done
+func (o *App) reloadOnHangup(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-hup:
+			o.Reload()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Reload reads the configuration again then applies what can change at
+// runtime: alerts, filters and the refresh interval. Collected metrics
+// are kept. Changes requiring a restart are logged then ignored.
+func (o *App) Reload() (config.Changes, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	conf, changes, err := config.Reload(o.conf)
+	if err != nil {
+		logrus.Errorf("config reload - %s, configuration unchanged", err)
+		return changes, err
+	}
+
+	o.backend.Reload(conf)
+	o.refresh.Store(int64(conf.UI.Refresh))
+	if o.server != nil {
+		o.server.SetConfig(conf)
+	}
+	o.conf = conf
+
+	logrus.Infof("config reload - applied: %v", changes.Applied)
+	for _, key := range changes.Restart {
+		logrus.Warnf("config reload - %s changed, restart to apply it", key)
+	}
+	return changes, nil
+}
+
+// silenceAlerts silences every active and pending alert for uiSilence,
+// one silence per alert matching its labels
+func (o *App) silenceAlerts() {
+	now := time.Now()
+	for _, t := range o.backend.AlertStates() {
+		if t.Alert.State() == alert.Inactive || t.Silenced {
+			continue
+		}
+		s, err := alert.NewSilence(t.Alert.Labels().Matchers(), now, now.Add(uiSilence), "silenced from the Alerts page", alert.CreatedByUI)
+		if err != nil {
+			// Should never happen, alerts have an alertname label at least
+			panic(err)
+		}
+		o.backend.AddSilence(s)
+	}
+}
+
+// unsilenceAlerts removes the silences set from the Alerts page
+func (o *App) unsilenceAlerts() {
+	for _, s := range o.backend.Silences() {
+		if s.CreatedBy == alert.CreatedByUI {
+			o.backend.ExpireSilence(s.ID)
+		}
+	}
+}
+
 // updateDashboards reads current metrics' state then
 // feed them to their appropriate view
 func (o *App) updateDashboards() error {
@@ -65,13 +218,24 @@ func (o *App) updateDashboards() error {
 		return err
 	}
 	o.frontend.View().ReqsPerSec(reqPers)
-	o.frontend.View().Alerts(o.backend.Alerts())
+	o.frontend.View().Alerts(o.backend.Alerts(), o.backend.AlertHistory)
+
+	lat, err := o.quantileVectorMetric(metrics.LatencyN)
+	if err != nil {
+		return err
+	}
+	bytes, err := o.distributionMetric(metrics.BytesN)
+	if err != nil {
+		return err
+	}
+	o.frontend.View().Latency(lat, bytes)
 
 	rc, err := o.routePerStatusCounterMetric()
 	if err != nil {
 		return err
 	}
 	o.frontend.View().RoutesPerStatus(rc)
+	o.frontend.View().Ingestion(o.backend.Parser(), o.backend.ParseErrors(), o.backend.Dropped())
 
 	return err
 }
@@ -102,6 +266,32 @@ func (o *App) counterVectorMetric(name string) (metrics.CounterVector, error) {
 	return c, err
 }
 
+func (o *App) quantileVectorMetric(name string) (metrics.QuantileVector, error) {
+	m, err := o.backend.Metric(name)
+	if err != nil {
+		return metrics.QuantileVector{}, err
+	}
+	q, ok := m.(metrics.QuantileVector)
+	if !ok {
+		return metrics.QuantileVector{}, fmt.Errorf("interface cast error - expected QuantileVector")
+	}
+
+	return q, err
+}
+
+func (o *App) distributionMetric(name string) (metrics.Distribution, error) {
+	m, err := o.backend.Metric(name)
+	if err != nil {
+		return metrics.Distribution{}, err
+	}
+	d, ok := m.(metrics.Distribution)
+	if !ok {
+		return metrics.Distribution{}, fmt.Errorf("interface cast error - expected Distribution")
+	}
+
+	return d, err
+}
+
 func (o *App) routePerStatusCounterMetric() (metrics.RoutePerStatusCounter, error) {
 	m, err := o.backend.Metric(metrics.RoutesPerStatusN)
 	if err != nil {
@@ -116,6 +306,12 @@ func (o *App) routePerStatusCounterMetric() (metrics.RoutePerStatusCounter, erro
 }
 
 func (o *App) Close() {
+	if o.server != nil {
+		o.server.Close()
+	}
 	o.backend.Close()
-	o.frontend.Close()
+	o.notifier.Close()
+	if o.frontend != nil {
+		o.frontend.Close()
+	}
 }
@@ -1,7 +1,14 @@
 package backend
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/julnicolas/httpmon/pkg/alert"
@@ -9,87 +16,409 @@ import (
 )
 
 type AlertManager struct {
+	mutex sync.Mutex // guards alerts, they are read and reloaded concurrently
 	// period is the evaluation period for alerting rules
 	period time.Duration
-	// enabled list enabled alerts, keys are alert names
-	enabled map[alert.NameT]bool
-	alerts  map[alert.NameT]AlertStateTransition
-	reqPerS *alert.RequestsPerSecond
-	states  chan AlertStateTransition
+	// perMetric lists the alert groups evaluated on a metric update,
+	// keys are metric names
+	perMetric map[string][]alertGroup
+	alerts    map[alert.NameT]AlertStateTransition
+	reqPerS   *alert.RequestsPerSecond
+	reqIn     alert.RequestsPerSecondInput // reqPerS' configuration
+	rules     map[alert.NameT]*alert.FanOut
+	ratios    map[alert.NameT]*alert.ErrorRatio
+	absents   map[alert.NameT]*alert.Absent
+	// silences mute matching alerts, configured ones come first
+	silences    []alert.Silence
+	inhibitions []alert.Inhibition
+	states      chan AlertStateTransition
+	dropped     atomic.Uint64 // transitions dropped because states was full
+	// listeners are called on every published transition,
+	// they must not block
+	listeners []func(AlertStateTransition)
 }
 
 type AlertStateTransition struct {
 	// Prev is the previous alert state, if different
 	// than current, send it.
 	// Initialised to alert.Inactive
-	Prev          alert.State
-	Alert         alert.Alert
-	Time          int64 // Evaluation time in Unix seconds
+	Prev  alert.State
+	Alert alert.Alert
+	Time  int64 // Evaluation time in Unix seconds
+	// Silenced and Inhibited are true if the alert's notifications are muted,
+	// transitions are published when they change too
+	Silenced      bool
+	Inhibited     bool
 	publishedOnce bool
+	group         alert.NameT // name of the rule the alert is an instance of
+	metric        string      // name of the metric the alert is evaluated against
 }
 
-func NewAlertManager(eval time.Duration, in alert.RequestsPerSecondInput) *AlertManager {
-	enabled := make(map[alert.NameT]bool, 1)
-	enabled[alert.ReqPerS] = true
+// Muted returns true if the alert is silenced or inhibited
+func (o AlertStateTransition) Muted() bool {
+	return o.Silenced || o.Inhibited
+}
+
+// ErrSilenceNotFound is returned when expiring an unknown silence
+var ErrSilenceNotFound = errors.New("silence not found")
+
+// alertGroup evaluates one or more alerts against a metric
+type alertGroup interface {
+	Name() alert.NameT
+	Eval(m metrics.Metric) []alert.Alert
+}
+
+// single is a group of one alert
+type single struct {
+	alert alert.Alert
+}
+
+func (o single) Name() alert.NameT {
+	return o.alert.Name()
+}
+
+func (o single) Eval(m metrics.Metric) []alert.Alert {
+	o.alert.Eval(m)
+	return []alert.Alert{o.alert}
+}
 
-	return &AlertManager{
-		period:  eval,
-		enabled: enabled,
-		alerts:  make(map[alert.NameT]AlertStateTransition),
-		reqPerS: alert.NewRequestsPerSecond(in),
-		states:  make(chan AlertStateTransition, 100),
+// NewAlertManager creates an alert manager evaluating the requests per second
+// alert, rules, error ratios and absent traffic alerts, they must have been
+// checked with the configuration.
+// Silences and inhibitions mute the notifications of the alerts they match.
+func NewAlertManager(eval time.Duration, in alert.RequestsPerSecondInput, rules []alert.RuleInput, ratios []alert.ErrorRatioInput,
+	absents []alert.AbsentInput, silences []alert.Silence, inhibitions []alert.Inhibition) *AlertManager {
+	o := &AlertManager{
+		period:      eval,
+		alerts:      make(map[alert.NameT]AlertStateTransition),
+		reqPerS:     alert.NewRequestsPerSecond(in),
+		reqIn:       in,
+		rules:       make(map[alert.NameT]*alert.FanOut, len(rules)),
+		ratios:      make(map[alert.NameT]*alert.ErrorRatio, len(ratios)),
+		absents:     make(map[alert.NameT]*alert.Absent, len(absents)),
+		silences:    append([]alert.Silence{}, silences...),
+		inhibitions: inhibitions,
+		states:      make(chan AlertStateTransition, 100),
+	}
+	for _, r := range rules {
+		o.rules[r.Name] = alert.NewFanOut(r)
 	}
+	for _, r := range ratios {
+		o.ratios[r.Name] = alert.NewErrorRatio(r)
+	}
+	for _, a := range absents {
+		o.absents[a.Name] = alert.NewAbsent(a)
+	}
+	o.route()
+
+	return o
 }
 
-// Eval evaluates alerts, making them available in Alerts()
-// if alert state has changed
+// route groups alerts by the metric they are evaluated against
+func (o *AlertManager) route() {
+	o.perMetric = map[string][]alertGroup{
+		metrics.ReqsPerS: {single{o.reqPerS}},
+	}
+	for _, name := range sortedNames(o.rules) {
+		r := o.rules[name]
+		metric := r.Input().Metric
+		o.perMetric[metric] = append(o.perMetric[metric], r)
+	}
+	for _, name := range sortedNames(o.ratios) {
+		o.perMetric[metrics.StatusClassesN] = append(o.perMetric[metrics.StatusClassesN], single{o.ratios[name]})
+	}
+	for _, name := range sortedNames(o.absents) {
+		o.perMetric[metrics.LastSeenN] = append(o.perMetric[metrics.LastSeenN], single{o.absents[name]})
+	}
+}
+
+// Eval evaluates the alerts of metric m, making them available in Alerts()
+// if their state, or whether they are muted, has changed
 func (o *AlertManager) Eval(m metrics.Metric) {
-	for name, enabled := range o.enabled {
-		if enabled {
-			// Try to publish every evaluated alerts
-			// Keep in memory their previous state
-			a := o.evalAlert(m)
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 
+	type evaluated struct {
+		name alert.NameT
+		seen bool // evaluated before
+	}
+	var names []evaluated
+	for _, g := range o.perMetric[m.Name()] {
+		for _, a := range g.Eval(m) {
+			name := a.Name()
 			t, ok := o.alerts[name]
 			t.Alert = a
 			t.Time = a.EvalTime().Unix()
-			o.publish(t)
+			t.group = g.Name()
+			t.metric = m.Name()
+			o.alerts[name] = t
+			names = append(names, evaluated{name, ok})
+		}
+	}
+
+	// Mutes are computed once every alert is evaluated as
+	// inhibitions depend on other alerts' states
+	now := time.Now()
+	for _, e := range names {
+		// Try to publish every evaluated alerts
+		// Keep in memory their previous state
+		t := o.alerts[e.name]
+		silenced, inhibited := o.silenced(t.Alert, now), o.inhibited(t.Alert)
+		muteChanged := silenced != t.Silenced || inhibited != t.Inhibited
+		t.Silenced, t.Inhibited = silenced, inhibited
+		o.publish(t, muteChanged)
+
+		if e.seen {
+			t.Prev = t.Alert.State()
+			t.publishedOnce = true
+		}
+		o.alerts[e.name] = t
+	}
+}
+
+// silenced returns true if an active silence matches a at t
+func (o *AlertManager) silenced(a alert.Alert, t time.Time) bool {
+	labels := a.Labels()
+	for _, s := range o.silences {
+		if s.Mutes(labels, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// inhibited returns true if an active alert inhibits a
+func (o *AlertManager) inhibited(a alert.Alert) bool {
+	if len(o.inhibitions) == 0 {
+		return false
+	}
 
-			if ok {
-				t.Prev = t.Alert.State()
-				t.publishedOnce = true
+	labels := a.Labels()
+	for _, t := range o.alerts {
+		if t.Alert.State() != alert.Active {
+			continue
+		}
+		source := t.Alert.Labels()
+		for _, i := range o.inhibitions {
+			if i.Inhibits(source, labels) {
+				return true
 			}
-			o.alerts[name] = t
 		}
 	}
+	return false
 }
 
-// publish publishes an alert with its previous state if the current state is different
-// publish all alerts once in their inactive state so that clients can now what alerts
-// are going to be published.
-func (o *AlertManager) publish(t AlertStateTransition) {
-	if !t.publishedOnce || t.Prev != t.Alert.State() {
+// Metrics returns the sorted names of the metrics alerts are evaluated against
+func (o *AlertManager) Metrics() []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	names := make([]string, 0, len(o.perMetric))
+	for name := range o.perMetric {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// publish publishes an alert with its previous state if the current state is different,
+// or if muteChanged. Publish all alerts once in their inactive state so that clients
+// can now what alerts are going to be published. Transitions are dropped rather than
+// blocking evaluation while Alerts() is full, listeners still receive them.
+func (o *AlertManager) publish(t AlertStateTransition, muteChanged bool) {
+	if !t.publishedOnce || t.Prev != t.Alert.State() || muteChanged {
 		// Alerts are pointer receivers for most parts so they can be changed
 		// even after being transmitted
 		n := AlertStateTransition{
-			Prev:  t.Prev,
-			Alert: t.Alert.DeepCopy(),
-			Time:  t.Time,
+			Prev:      t.Prev,
+			Alert:     t.Alert.DeepCopy(),
+			Time:      t.Time,
+			Silenced:  t.Silenced,
+			Inhibited: t.Inhibited,
+		}
+		// Listeners first so that the history holds the transition
+		// once it's received
+		for _, l := range o.listeners {
+			l(n)
+		}
+		select {
+		case o.states <- n:
+		default:
+			o.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns the number of transitions dropped because Alerts() was full
+func (o *AlertManager) Dropped() uint64 {
+	return o.dropped.Load()
+}
+
+// Listen calls l on every published alert state transition, l is called
+// while alerts are evaluated so it must not block
+func (o *AlertManager) Listen(l func(AlertStateTransition)) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.listeners = append(o.listeners, l)
+}
+
+// Period returns the evaluation period of alerts
+func (o *AlertManager) Period() time.Duration {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.period
+}
+
+// Reload swaps alerts whose configuration changed, they restart from
+// the inactive state. Unchanged alerts keep their state, removed ones
+// are dropped. Changed and removed alerts which are pending or active
+// are published as resolved first. Configured silences and inhibitions
+// are replaced, silences created at runtime are kept.
+func (o *AlertManager) Reload(eval time.Duration, in alert.RequestsPerSecondInput, rules []alert.RuleInput, ratios []alert.ErrorRatioInput,
+	absents []alert.AbsentInput, silences []alert.Silence, inhibitions []alert.Inhibition) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	kept := append([]alert.Silence{}, silences...)
+	for _, s := range o.silences {
+		if s.CreatedBy != alert.CreatedByConfig {
+			kept = append(kept, s)
+		}
+	}
+	o.silences = kept
+	o.inhibitions = inhibitions
+	o.period = eval
+
+	reqPerS := o.reqPerS
+	if in != o.reqIn {
+		reqPerS = alert.NewRequestsPerSecond(in)
+	}
+
+	current := make(map[alert.NameT]*alert.FanOut, len(rules))
+	for _, r := range rules {
+		if old, ok := o.rules[r.Name]; ok && reflect.DeepEqual(old.Input(), r) {
+			current[r.Name] = old
+		} else {
+			current[r.Name] = alert.NewFanOut(r)
+		}
+	}
+	currentRatios := make(map[alert.NameT]*alert.ErrorRatio, len(ratios))
+	for _, r := range ratios {
+		if old, ok := o.ratios[r.Name]; ok && reflect.DeepEqual(old.Input(), r) {
+			currentRatios[r.Name] = old
+		} else {
+			currentRatios[r.Name] = alert.NewErrorRatio(r)
 		}
-		o.states <- n
 	}
+	currentAbsents := make(map[alert.NameT]*alert.Absent, len(absents))
+	for _, a := range absents {
+		if old, ok := o.absents[a.Name]; ok && old.Input() == a {
+			currentAbsents[a.Name] = old
+		} else {
+			currentAbsents[a.Name] = alert.NewAbsent(a)
+		}
+	}
+
+	// replaced returns true if group was removed or swapped
+	replaced := func(group alert.NameT) bool {
+		if group == alert.ReqPerS {
+			return reqPerS != o.reqPerS
+		}
+		if r, ok := current[group]; ok {
+			return r != o.rules[group]
+		}
+		if r, ok := currentRatios[group]; ok {
+			return r != o.ratios[group]
+		}
+		if a, ok := currentAbsents[group]; ok {
+			return a != o.absents[group]
+		}
+		return true
+	}
+	for _, name := range sortedNames(o.alerts) {
+		if t := o.alerts[name]; replaced(t.group) {
+			o.resolve(t)
+			delete(o.alerts, name)
+		}
+	}
+	o.reqPerS = reqPerS
+	o.reqIn = in
+	o.rules = current
+	o.ratios = currentRatios
+	o.absents = currentAbsents
+	o.route()
+}
+
+// resolve publishes t as inactive if it is pending or active, as of its
+// last evaluation, so that notifications and history are closed
+func (o *AlertManager) resolve(t AlertStateTransition) {
+	if t.Alert.State() == alert.Inactive {
+		return
+	}
+	t.Prev = t.Alert.State()
+	t.Alert = resolved{t.Alert}
+	t.publishedOnce = true
+	o.publish(t, false)
+}
+
+// resolved is an alert dropped by a reload, it is inactive for good
+type resolved struct {
+	alert.Alert
+}
+
+func (o resolved) State() alert.State {
+	return alert.Inactive
 }
 
-func (o *AlertManager) evalAlert(m metrics.Metric) alert.Alert {
-	switch m.Name() {
-	case metrics.ReqsPerS:
-		o.reqPerS.Eval(m)
-		return o.reqPerS
-	default:
-		// Should never happen so let's get notified early on
-		err := fmt.Errorf("configuration error - alert %s is not supported", m.Name())
+func (o resolved) DeepCopy() alert.Alert {
+	return resolved{o.Alert.DeepCopy()}
+}
+
+// AddSilence adds a silence created at runtime then returns it with its ID,
+// it applies from the next evaluation
+func (o *AlertManager) AddSilence(s alert.Silence) alert.Silence {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		// Should never happen, crypto/rand never fails on supported platforms
 		panic(err)
 	}
+	s.ID = hex.EncodeToString(id)
+	o.silences = append(o.silences, s)
+
+	return s
+}
+
+// ExpireSilence removes a silence created at runtime, configured ones
+// can only be removed from the configuration
+func (o *AlertManager) ExpireSilence(id string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for i, s := range o.silences {
+		if s.ID != id {
+			continue
+		}
+		if s.CreatedBy == alert.CreatedByConfig {
+			return fmt.Errorf("silence %s is configured, remove it from the configuration instead", id)
+		}
+		o.silences = append(o.silences[:i], o.silences[i+1:]...)
+		return nil
+	}
+	return ErrSilenceNotFound
+}
+
+// Silences returns a copy of the silences, expired ones included
+func (o *AlertManager) Silences() []alert.Silence {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return append([]alert.Silence{}, o.silences...)
 }
 
 // Alerts only exposes alerts which state's have changed
@@ -97,3 +426,61 @@ func (o *AlertManager) evalAlert(m metrics.Metric) alert.Alert {
 func (o *AlertManager) Alerts() <-chan AlertStateTransition {
 	return o.states
 }
+
+// States returns a copy of the last evaluated state of every alert,
+// sorted by alert name. It is thread-safe.
+func (o *AlertManager) States() []AlertStateTransition {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	states := make([]AlertStateTransition, 0, len(o.alerts))
+	for _, t := range o.alerts {
+		states = append(states, AlertStateTransition{
+			Prev:      t.Prev,
+			Alert:     t.Alert.DeepCopy(),
+			Time:      t.Time,
+			Silenced:  t.Silenced,
+			Inhibited: t.Inhibited,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Alert.Name() < states[j].Alert.Name()
+	})
+
+	return states
+}
+
+// state returns the internal state of every evaluated alert for debugging
+func (o *AlertManager) state() []AlertState {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	states := make([]AlertState, 0, len(o.alerts))
+	for name, t := range o.alerts {
+		states = append(states, AlertState{
+			Name:          string(name),
+			Metric:        t.metric,
+			State:         t.Alert.State().String(),
+			Prev:          t.Prev.String(),
+			EvalTime:      time.Unix(t.Time, 0),
+			PublishedOnce: t.publishedOnce,
+			Silenced:      t.Silenced,
+			Inhibited:     t.Inhibited,
+			Description:   t.Alert.Description(),
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+
+	return states
+}
+
+// sortedNames returns the sorted keys of alerts
+func sortedNames[T any](alerts map[alert.NameT]T) []alert.NameT {
+	names := make([]alert.NameT, 0, len(alerts))
+	for name := range alerts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	return names
+}
@@ -0,0 +1,171 @@
+package backend
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertManagerRoutesRulesByMetric(t *testing.T) {
+	reqIn := alert.RequestsPerSecondInput{Period: time.Minute, Threshold: 10}
+	rule := alert.RuleInput{
+		Name:        "errors",
+		Metric:      metrics.RoutesPerStatusN,
+		Selector:    map[string]string{"status": "500"},
+		Aggregation: alert.Last,
+		Operator:    alert.GreaterOrEqual,
+		Threshold:   1,
+	}
+	o := NewAlertManager(time.Second, reqIn, []alert.RuleInput{rule}, nil, nil, nil, nil)
+	assert.Equal(t, []string{metrics.ReqsPerS, metrics.RoutesPerStatusN}, o.Metrics())
+
+	p := metrics.NewRoutePerStatus()
+	p.Update(trace.Trace{Date: time.Unix(100, 0), Section: "/api", Status: 500})
+	o.Eval(p.DeepCopy())
+	o.Eval(p.DeepCopy())
+
+	states := o.States()
+	assert.Len(t, states, 1)
+	assert.Equal(t, alert.NameT("errors"), states[0].Alert.Name())
+	assert.Equal(t, alert.Pending, states[0].Alert.State())
+
+	// Unchanged rules keep their state
+	o.Reload(time.Second, reqIn, []alert.RuleInput{rule}, nil, nil, nil, nil)
+	assert.Equal(t, alert.Pending, o.States()[0].Alert.State())
+
+	o.Reload(time.Second, reqIn, nil, nil, nil, nil, nil)
+	assert.Empty(t, o.States())
+	assert.Equal(t, []string{metrics.ReqsPerS}, o.Metrics())
+}
+
+func TestAlertManagerResolvesAlertsDroppedByReload(t *testing.T) {
+	reqIn := alert.RequestsPerSecondInput{Period: time.Minute, Threshold: 10}
+	errors := alert.RuleInput{
+		Name:        "errors",
+		Metric:      metrics.RoutesPerStatusN,
+		Selector:    map[string]string{"status": "500"},
+		Aggregation: alert.Last,
+		Operator:    alert.GreaterOrEqual,
+		Threshold:   1,
+	}
+	perSection := errors
+	perSection.Name = "section errors"
+	perSection.By = "section"
+	o := NewAlertManager(time.Second, reqIn, []alert.RuleInput{errors, perSection}, nil, nil, nil, nil)
+	var published []AlertStateTransition
+	o.Listen(func(t AlertStateTransition) { published = append(published, t) })
+
+	p := metrics.NewRoutePerStatus()
+	for date := int64(100); date <= 130; date += 10 {
+		p.Update(trace.Trace{Date: time.Unix(date, 0), Section: "/api", Status: 500})
+		o.Eval(p.DeepCopy())
+	}
+	for _, s := range o.States() {
+		assert.Equal(t, alert.Active, s.Alert.State())
+	}
+
+	// errors changes, section errors is removed
+	changed := errors
+	changed.Threshold = 100
+	published = nil
+	o.Reload(time.Second, reqIn, []alert.RuleInput{changed}, nil, nil, nil, nil)
+
+	assert.Len(t, published, 2)
+	for i, name := range []alert.NameT{"errors", `section errors{section="/api"}`} {
+		assert.Equal(t, name, published[i].Alert.Name())
+		assert.Equal(t, alert.Active, published[i].Prev)
+		assert.Equal(t, alert.Inactive, published[i].Alert.State())
+		assert.Equal(t, int64(130), published[i].Time)
+	}
+	assert.Empty(t, o.States())
+
+	// The changed rule restarts from the inactive state
+	published = nil
+	o.Eval(p.DeepCopy())
+	assert.Len(t, published, 1)
+	assert.Equal(t, alert.Inactive, published[0].Prev)
+	assert.Equal(t, alert.Inactive, published[0].Alert.State())
+}
+
+func TestAlertManagerMutesAlerts(t *testing.T) {
+	errors := alert.RuleInput{
+		Name:        "errors",
+		Metric:      metrics.RoutesPerStatusN,
+		Selector:    map[string]string{"status": "500"},
+		Aggregation: alert.Last,
+		Operator:    alert.GreaterOrEqual,
+		Threshold:   1,
+	}
+	perSection := errors
+	perSection.Name = "section errors"
+	perSection.By = "section"
+	// Global errors inhibit per-section ones
+	inhibition := alert.NewInhibition(map[string]string{alert.AlertName: "errors"}, map[string]string{"section": ".+"}, nil)
+	silence, err := alert.NewSilence(map[string]string{"section": "/help"}, time.Now().Add(-time.Minute), time.Now().Add(time.Hour), "", alert.CreatedByConfig)
+	assert.NoError(t, err)
+	silence.ID = "config-0"
+
+	o := NewAlertManager(time.Second, alert.RequestsPerSecondInput{Period: time.Minute, Threshold: 10},
+		[]alert.RuleInput{errors, perSection}, nil, nil, []alert.Silence{silence}, []alert.Inhibition{inhibition})
+	p := metrics.NewRoutePerStatus()
+	p.Update(trace.Trace{Date: time.Unix(100, 0), Section: "/api", Status: 500})
+	p.Update(trace.Trace{Date: time.Unix(100, 0), Section: "/help", Status: 500})
+	o.Eval(p.DeepCopy())
+	p.Update(trace.Trace{Date: time.Unix(110, 0), Section: "/api", Status: 500})
+	o.Eval(p.DeepCopy())
+
+	muted := func() map[alert.NameT][2]bool {
+		m := map[alert.NameT][2]bool{}
+		for _, t := range o.States() {
+			m[t.Alert.Name()] = [2]bool{t.Silenced, t.Inhibited}
+		}
+		return m
+	}
+	assert.Equal(t, map[alert.NameT][2]bool{
+		"errors":                          {false, false},
+		`section errors{section="/api"}`:  {false, true},
+		`section errors{section="/help"}`: {true, true},
+	}, muted())
+
+	s, err := alert.NewSilence(map[string]string{alert.AlertName: "errors"}, time.Now(), time.Now().Add(time.Hour), "load test", alert.CreatedByAPI)
+	assert.NoError(t, err)
+	s = o.AddSilence(s)
+	o.Eval(p.DeepCopy())
+	assert.Equal(t, [2]bool{true, false}, muted()["errors"])
+
+	assert.NoError(t, o.ExpireSilence(s.ID))
+	assert.ErrorIs(t, o.ExpireSilence(s.ID), ErrSilenceNotFound)
+	assert.Error(t, o.ExpireSilence("config-0"))
+	assert.Len(t, o.Silences(), 1)
+}
+
+func TestAlertManagerDropsTransitionsInsteadOfBlocking(t *testing.T) {
+	rule := alert.RuleInput{
+		Name:        "busy",
+		Metric:      metrics.RoutesPerStatusN,
+		By:          "section",
+		Aggregation: alert.Last,
+		Operator:    alert.GreaterOrEqual,
+		Threshold:   1,
+	}
+	o := NewAlertManager(time.Second, alert.RequestsPerSecondInput{Period: time.Minute, Threshold: 10},
+		[]alert.RuleInput{rule}, nil, nil, nil, nil)
+	published := 0
+	o.Listen(func(AlertStateTransition) { published++ })
+
+	p := metrics.NewRoutePerStatus()
+	for i := 0; i < 150; i++ {
+		p.Update(trace.Trace{Date: time.Unix(100, 0), Section: fmt.Sprintf("/%d", i), Status: 200})
+	}
+	// Alerts() is never read
+	o.Eval(p.DeepCopy())
+
+	assert.Equal(t, 150, published)
+	assert.Equal(t, uint64(50), o.Dropped())
+	assert.Len(t, o.Alerts(), 100)
+}
@@ -3,20 +3,31 @@ package backend
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/julnicolas/httpmon/pkg/alert"
 	"github.com/julnicolas/httpmon/pkg/config"
 	"github.com/julnicolas/httpmon/pkg/metrics"
 	"github.com/julnicolas/httpmon/pkg/parser"
 	"github.com/julnicolas/httpmon/pkg/reader"
+	"github.com/julnicolas/httpmon/pkg/trace"
 )
 
 type Backend struct {
 	ingestor  *Ingestor
 	collector *MetricsCollector
 	alertor   *AlertManager
-	ingestErr error // not nil if an error occurs in ingestor.Ingest()
-	pollErr   error // not nil if an error occurs in ingestor.Poll()
+	history   *History
+	reorder   *ReorderBuffer
+	clock     *StreamClock // time alerts are evaluated at
+	nextEval  time.Time    // clock time of the next alert evaluation
+	// ingestErr and pollErr are set by Run's goroutines then read
+	// concurrently, by the app and the /debug/state endpoint
+	ingestErr atomic.Pointer[error] // set if an error occurs in ingestor.Ingest()
+	pollErr   atomic.Pointer[error] // set if an error occurs processing traces
+	opened    atomic.Bool           // the input stream has been opened
+	processed atomic.Bool           // at least one trace has been processed
 }
 
 // Creates a new backend object
@@ -25,10 +36,14 @@ type Backend struct {
 // func NewBackend(file string, readBufferLen uint, alertor *AlertManager) *Backend {
 func NewBackend(conf config.Config) *Backend {
 	// TODO: could be moved in MetricsCollector based on a config object?
-	probers := make([]metrics.Prober, 0, 3)
+	probers := make([]metrics.Prober, 0, 7)
 	probers = append(probers, metrics.NewRequestsPerHost())
 	probers = append(probers, metrics.NewRoutePerStatus())
-	probers = append(probers, metrics.NewRequestsPerSecond(conf.Period)) // Atta
+	probers = append(probers, metrics.NewRequestsPerSecond(conf.Period, int(conf.Retention))) // Atta
+	probers = append(probers, metrics.NewLatency(conf.Period, int(conf.Retention)))
+	probers = append(probers, metrics.NewBytes(conf.Period, int(conf.Retention)))
+	probers = append(probers, metrics.NewStatusClasses(conf.Period, int(conf.Retention)))
+	probers = append(probers, metrics.NewLastSeen())
 
 	var r reader.Reader
 	if strings.ToLower(conf.File) == "stdin" {
@@ -37,24 +52,119 @@ func NewBackend(conf config.Config) *Backend {
 		r = reader.NewTailer(conf.ReadBufferSize)
 	}
 
+	ingestor := newIngestor(conf, r)
+	ingestor.SetFilter(NewFilter(conf.Filter))
+
+	alertor := NewAlertManager(conf.Alert.Period, reqPerSInput(conf.Alert), ruleInputs(conf.Alert), errorRatioInputs(conf.Alert),
+		absentInputs(conf.Alert), silences(conf.Alert), inhibitions(conf.Alert))
+	history := NewHistory(conf.History.File, conf.History.Retention)
+	alertor.Listen(history.Record)
+
 	return &Backend{
-		ingestor: NewIngestor(
-			conf.File,
-			r,
-			parser.NewCSV(),
-			conf.ReadBufferSize,
-		),
+		ingestor:  ingestor,
 		collector: NewMetricsCollector(probers),
-		alertor: NewAlertManager(conf.Alert.Period,
-			alert.RequestsPerSecondInput{
-				Period:    conf.Alert.RequestsPerSecond.Period,
-				Threshold: conf.Alert.RequestsPerSecond.Threshold,
-			}),
+		reorder:   NewReorderBuffer(conf.Lateness),
+		clock:     NewStreamClock(WallClock{}),
+		alertor:   alertor,
+		history:   history,
+	}
+}
+
+func reqPerSInput(conf config.Alert) alert.RequestsPerSecondInput {
+	return alert.RequestsPerSecondInput{
+		Period:    conf.RequestsPerSecond.Period,
+		Threshold: conf.RequestsPerSecond.Threshold,
+		Resolve:   conf.RequestsPerSecond.Resolve.Input(),
+	}
+}
+
+func ruleInputs(conf config.Alert) []alert.RuleInput {
+	rules := make([]alert.RuleInput, 0, len(conf.Rules))
+	for _, r := range conf.Rules {
+		rules = append(rules, r.Input())
+	}
+	return rules
+}
+
+func errorRatioInputs(conf config.Alert) []alert.ErrorRatioInput {
+	ratios := make([]alert.ErrorRatioInput, 0, len(conf.ErrorRatios))
+	for _, r := range conf.ErrorRatios {
+		ratios = append(ratios, r.Input())
+	}
+	return ratios
+}
+
+func absentInputs(conf config.Alert) []alert.AbsentInput {
+	absents := make([]alert.AbsentInput, 0, len(conf.Absent))
+	for _, a := range conf.Absent {
+		absents = append(absents, a.Input())
+	}
+	return absents
+}
+
+// silences returns the configured silences, their IDs are config-<index>
+func silences(conf config.Alert) []alert.Silence {
+	silences := make([]alert.Silence, 0, len(conf.Silences))
+	for i, s := range conf.Silences {
+		silences = append(silences, s.Input(fmt.Sprintf("%s-%d", alert.CreatedByConfig, i)))
+	}
+	return silences
+}
+
+func inhibitions(conf config.Alert) []alert.Inhibition {
+	inhibitions := make([]alert.Inhibition, 0, len(conf.Inhibitions))
+	for _, in := range conf.Inhibitions {
+		inhibitions = append(inhibitions, in.Input())
 	}
+	return inhibitions
+}
+
+// Reload applies the configuration which can change at runtime,
+// alerts and filters. Collected metrics are kept.
+func (o *Backend) Reload(conf config.Config) {
+	o.ingestor.SetFilter(NewFilter(conf.Filter))
+	o.alertor.Reload(conf.Alert.Period, reqPerSInput(conf.Alert), ruleInputs(conf.Alert), errorRatioInputs(conf.Alert),
+		absentInputs(conf.Alert), silences(conf.Alert), inhibitions(conf.Alert))
+}
+
+// newIngestor creates an ingestor using the configured parser,
+// or detecting it in auto mode
+func newIngestor(conf config.Config, r reader.Reader) *Ingestor {
+	opts, err := conf.Parser.Options()
+	if err != nil {
+		// Validated with the configuration, should never happen
+		panic(err)
+	}
+
+	if conf.Parser.Name == config.AutoParser {
+		return NewDetectingIngestor(conf.File, r, opts, conf.Parser.DetectLines, conf.ReadBufferSize, conf.UI.Headless)
+	}
+
+	p, err := parser.New(conf.Parser.Name, opts)
+	if err != nil {
+		// Validated with the configuration, should never happen
+		panic(err)
+	}
+
+	return NewIngestor(conf.File, r, conf.Parser.Name, p, conf.ReadBufferSize)
 }
 
 func (o *Backend) Init() error {
-	return o.ingestor.Init()
+	if err := o.history.Open(); err != nil {
+		return fmt.Errorf("alert history - %w", err)
+	}
+	if err := o.ingestor.Init(); err != nil {
+		return err
+	}
+
+	o.opened.Store(true)
+	return nil
+}
+
+// Ready returns true once the input stream is opened
+// and a first trace has been processed
+func (o *Backend) Ready() bool {
+	return o.opened.Load() && o.processed.Load()
 }
 
 // runErr concatenates all errors that occured in run in a single message
@@ -77,8 +187,15 @@ func (o runErr) Error() string {
 // RunErr returns non nil if an error occured in Run
 // Useful if backend is run in another goroutine
 func (o *Backend) RunErr() error {
-	if o.ingestErr != nil || o.pollErr != nil {
-		return runErr{ingestErr: o.ingestErr, pollErr: o.pollErr}
+	var err runErr
+	if p := o.ingestErr.Load(); p != nil {
+		err.ingestErr = *p
+	}
+	if p := o.pollErr.Load(); p != nil {
+		err.pollErr = *p
+	}
+	if err.ingestErr != nil || err.pollErr != nil {
+		return err
 	}
 
 	return nil
@@ -88,25 +205,88 @@ func (o *Backend) Run() error {
 	go func() {
 		for {
 			if err := o.ingestor.Ingest(); err != nil {
-				o.ingestErr = err
+				o.ingestErr.Store(&err)
 				return
 			}
 		}
 	}()
 
+	// Checks whether the stream is idle to flush traces held
+	// by the reorder buffer
+	idle := max(o.reorder.lateness, time.Second)
+	ticker := time.NewTicker(idle)
+	defer ticker.Stop()
+	lastReceived := time.Now()
+
+	// Evaluates alerts while traffic is silent
+	period := o.alertor.Period()
+	evalTicker := time.NewTicker(period)
+	defer evalTicker.Stop()
+
 	for {
-		t := o.ingestor.Poll()
-		if err := o.collector.Collect(t); err != nil {
-			o.pollErr = err
-			return err
+		var ready []trace.Trace
+		select {
+		case t := <-o.ingestor.Traces():
+			lastReceived = time.Now()
+			ready = o.reorder.Push(t)
+		case <-ticker.C:
+			if time.Since(lastReceived) < idle {
+				continue
+			}
+			ready = o.reorder.Flush()
+		case <-evalTicker.C:
+			// The period can be reloaded
+			if p := o.alertor.Period(); p != period {
+				period = p
+				evalTicker.Reset(period)
+			}
+		}
+
+		for _, t := range ready {
+			if err := o.process(t); err != nil {
+				o.pollErr.Store(&err)
+				return err
+			}
 		}
+		o.tick()
+	}
+}
+
+// process collects metrics from a trace
+func (o *Backend) process(t trace.Trace) error {
+	if err := o.collector.Collect(t); err != nil {
+		return err
+	}
 
-		m, err := o.Metric(metrics.ReqsPerS)
+	o.clock.Observe(t.Date)
+	o.processed.Store(true)
+	return nil
+}
+
+// tick evaluates alerts once the clock reaches the next evaluation,
+// evaluations are aligned on multiples of the alert period
+func (o *Backend) tick() {
+	now, period := o.clock.Now(), o.alertor.Period()
+	// The clock moves back when it switches from wall time
+	// to the date of the first trace of a replay
+	if now.Before(o.nextEval) && !now.Before(o.nextEval.Add(-period)) {
+		return
+	}
+
+	o.evaluate(now)
+	o.nextEval = now.Truncate(period).Add(period)
+}
+
+// evaluate closes the metrics' periods ended before now
+// then evaluates all alerts from updated metric values
+func (o *Backend) evaluate(now time.Time) {
+	o.collector.Advance(now)
+	for _, name := range o.alertor.Metrics() {
+		m, err := o.Metric(name)
 		if err != nil {
+			// Rules' metrics are checked with the configuration
 			panic(err)
 		}
-
-		// Evaluate all alerts from updated metric values
 		o.alertor.Eval(m)
 	}
 }
@@ -118,12 +298,67 @@ func (o *Backend) Metric(name string) (metrics.Metric, error) {
 	return o.collector.DeepCopy(name)
 }
 
+// Parser returns the name of the parser in use,
+// empty while the log format is being detected
+func (o *Backend) Parser() string {
+	return o.ingestor.Parser()
+}
+
+// ParseErrors returns the number of log lines which could not be parsed
+func (o *Backend) ParseErrors() uint64 {
+	return o.ingestor.ParseErrors()
+}
+
+// Dropped returns the number of traces dropped because they were received
+// too late to be reordered
+func (o *Backend) Dropped() uint64 {
+	return o.reorder.Dropped()
+}
+
+// DroppedAlerts returns the number of alert state transitions dropped
+// because Alerts() was not read fast enough
+func (o *Backend) DroppedAlerts() uint64 {
+	return o.alertor.Dropped()
+}
+
 // Alerts only exposes alerts which state's have changed
 // Every alert is at least sent once when it is initialises as inactive
 func (o *Backend) Alerts() <-chan AlertStateTransition {
 	return o.alertor.Alerts()
 }
 
+// OnAlert calls l on every alert state transition published on Alerts(),
+// l must not block
+func (o *Backend) OnAlert(l func(AlertStateTransition)) {
+	o.alertor.Listen(l)
+}
+
+// AddSilence adds a silence at runtime, see AlertManager.AddSilence
+func (o *Backend) AddSilence(s alert.Silence) alert.Silence {
+	return o.alertor.AddSilence(s)
+}
+
+// ExpireSilence removes a silence added at runtime
+func (o *Backend) ExpireSilence(id string) error {
+	return o.alertor.ExpireSilence(id)
+}
+
+// Silences returns every silence, configured ones first
+func (o *Backend) Silences() []alert.Silence {
+	return o.alertor.Silences()
+}
+
+// AlertHistory returns the alert state changes selected by q, oldest first
+func (o *Backend) AlertHistory(q HistoryQuery) []HistoryEntry {
+	return o.history.Query(q)
+}
+
+// AlertStates returns the last evaluated state of every alert
+func (o *Backend) AlertStates() []AlertStateTransition {
+	return o.alertor.States()
+}
+
 func (o *Backend) Close() error {
+	o.history.Close()
 	return o.ingestor.Close()
 }
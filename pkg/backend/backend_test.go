@@ -0,0 +1,90 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// stateOf returns the state of alert name
+func stateOf(o *Backend, name alert.NameT) alert.State {
+	for _, t := range o.AlertStates() {
+		if t.Alert.Name() == name {
+			return t.Alert.State()
+		}
+	}
+	return alert.Inactive
+}
+
+func TestBackendEvaluatesAlertsWhileTrafficIsSilent(t *testing.T) {
+	conf := config.Default()
+	conf.Period = time.Second
+	conf.Alert.RequestsPerSecond = config.RequestsPerSecond{Period: 2 * time.Second, Threshold: 2}
+	o := NewBackend(conf)
+	wall := &fakeClock{time.Unix(5000, 0)}
+	o.clock = NewStreamClock(wall)
+
+	// Replayed traces, 5 req/s
+	for s := int64(100); s < 110; s++ {
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, o.process(traceAt(s, "/api")))
+			o.tick()
+		}
+	}
+	assert.Equal(t, alert.Active, stateOf(o, alert.ReqPerS))
+
+	// No trace is processed anymore
+	for i := 0; i < 5; i++ {
+		wall.now = wall.now.Add(time.Second)
+		o.tick()
+	}
+	assert.Equal(t, alert.Inactive, stateOf(o, alert.ReqPerS))
+}
+
+func TestBackendDetectsAbsentTraffic(t *testing.T) {
+	conf := config.Default()
+	conf.Alert.Absent = []config.Absent{
+		{Name: "dead", After: 10 * time.Second},
+		{Name: "dead report", Section: "/report", After: 10 * time.Second},
+	}
+	o := NewBackend(conf)
+	wall := &fakeClock{time.Unix(5000, 0)}
+	o.clock = NewStreamClock(wall)
+
+	// Nothing was ever received
+	for i := 0; i < 12; i++ {
+		wall.now = wall.now.Add(time.Second)
+		o.tick()
+	}
+	assert.Equal(t, alert.Active, stateOf(o, "dead"))
+
+	// Replayed traces are evaluated in log time
+	for s := int64(100); s < 130; s++ {
+		assert.NoError(t, o.process(traceAt(s, "/api")))
+		o.tick()
+	}
+	assert.Equal(t, alert.Inactive, stateOf(o, "dead"))
+	assert.Equal(t, alert.Active, stateOf(o, "dead report"))
+
+	// Tailed traffic stops
+	for i := 0; i < 12; i++ {
+		wall.now = wall.now.Add(time.Second)
+		o.tick()
+	}
+	assert.Equal(t, alert.Active, stateOf(o, "dead"))
+}
+
+func TestBackendRunErrIsReadWhileRunning(t *testing.T) {
+	conf := config.Default()
+	o := NewBackend(conf)
+	o.ingestor.reader = &fakeReader{err: errors.New("broken")}
+	go o.Run()
+
+	// Read concurrently the way /debug/state does, checked with -race
+	assert.Eventually(t, func() bool { return o.State().RunErr != "" }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "ingest: broken; ", o.RunErr().Error())
+}
@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time alerts are evaluated at
+type Clock interface {
+	Now() time.Time
+}
+
+// WallClock is the system's clock
+type WallClock struct{}
+
+func (WallClock) Now() time.Time {
+	return time.Now()
+}
+
+// StreamClock follows the date of the latest processed trace, event time,
+// extrapolated with wall time while no trace is processed. Replayed logs
+// are evaluated at their own pace, tailed ones in real time, so that
+// periods without traffic are still evaluated. It is wall time until
+// a first trace is processed.
+type StreamClock struct {
+	mutex sync.Mutex
+	wall  Clock
+	event time.Time // date of the latest processed trace
+	at    time.Time // wall time event was observed at
+}
+
+// NewStreamClock creates a clock extrapolating event time with wall
+func NewStreamClock(wall Clock) *StreamClock {
+	return &StreamClock{wall: wall}
+}
+
+// Observe moves the clock to date, older dates are ignored
+func (o *StreamClock) Observe(date time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if !date.After(o.event) {
+		return
+	}
+	o.event = date
+	o.at = o.wall.Now()
+}
+
+// Now returns the current event time, wall time until a first date is observed
+func (o *StreamClock) Now() time.Time {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.event.IsZero() {
+		return o.wall.Now()
+	}
+	return o.event.Add(o.wall.Now().Sub(o.at))
+}
@@ -0,0 +1,35 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (o *fakeClock) Now() time.Time { return o.now }
+
+func TestStreamClockExtrapolatesEventTime(t *testing.T) {
+	wall := &fakeClock{time.Unix(5000, 0)}
+	o := NewStreamClock(wall)
+	assert.Equal(t, wall.now, o.Now())
+
+	o.Observe(time.Unix(100, 0))
+	assert.Equal(t, time.Unix(100, 0), o.Now())
+
+	// Replayed traces are processed faster than real time
+	o.Observe(time.Unix(160, 0))
+	assert.Equal(t, time.Unix(160, 0), o.Now())
+
+	// Older dates don't move the clock back
+	o.Observe(time.Unix(150, 0))
+	assert.Equal(t, time.Unix(160, 0), o.Now())
+
+	// Silent traffic
+	wall.now = wall.now.Add(30 * time.Second)
+	assert.Equal(t, time.Unix(190, 0), o.Now())
+}
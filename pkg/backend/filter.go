@@ -0,0 +1,36 @@
+package backend
+
+import (
+	"github.com/julnicolas/httpmon/pkg/config"
+	"github.com/julnicolas/httpmon/pkg/trace"
+)
+
+// Filter selects the traces metrics are collected from
+type Filter struct {
+	sections     map[string]bool // kept sections, all if empty
+	excludeHosts map[string]bool
+}
+
+// NewFilter creates a filter from its configuration
+func NewFilter(conf config.Filter) *Filter {
+	return &Filter{
+		sections:     set(conf.Sections),
+		excludeHosts: set(conf.ExcludeHosts),
+	}
+}
+
+// Keep returns true if metrics must be collected from t
+func (o *Filter) Keep(t trace.Trace) bool {
+	if len(o.sections) > 0 && !o.sections[t.Section] {
+		return false
+	}
+	return !o.excludeHosts[t.RemoteHost]
+}
+
+func set(values []string) map[string]bool {
+	s := make(map[string]bool, len(values))
+	for _, v := range values {
+		s[v] = true
+	}
+	return s
+}
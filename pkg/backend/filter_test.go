@@ -0,0 +1,20 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/julnicolas/httpmon/pkg/config"
+	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	f := NewFilter(config.Filter{Sections: []string{"/api"}, ExcludeHosts: []string{"10.0.0.1"}})
+
+	assert.True(t, f.Keep(trace.Trace{Section: "/api", RemoteHost: "10.0.0.2"}))
+	assert.False(t, f.Keep(trace.Trace{Section: "/report", RemoteHost: "10.0.0.2"}))
+	assert.False(t, f.Keep(trace.Trace{Section: "/api", RemoteHost: "10.0.0.1"}))
+
+	all := NewFilter(config.Filter{}.Default())
+	assert.True(t, all.Keep(trace.Trace{Section: "/report", RemoteHost: "10.0.0.1"}))
+}
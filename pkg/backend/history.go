@@ -0,0 +1,288 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/sirupsen/logrus"
+)
+
+// HistoryEntry is an alert state change
+type HistoryEntry struct {
+	Time        time.Time   `json:"time"` // evaluation time, in log time
+	Alert       alert.NameT `json:"alert"`
+	State       alert.State `json:"state"`
+	Prev        alert.State `json:"prev"`
+	Silenced    bool        `json:"silenced,omitempty"`
+	Inhibited   bool        `json:"inhibited,omitempty"`
+	Description string      `json:"description"`
+}
+
+// HistoryQuery selects history entries, zero values select everything
+type HistoryQuery struct {
+	Alert  alert.NameT
+	States []alert.State
+	From   time.Time // included
+	To     time.Time // excluded
+	Limit  int       // maximum number of entries, the latest ones are kept
+}
+
+// match returns true if e is selected by o
+func (o HistoryQuery) match(e HistoryEntry) bool {
+	return (o.Alert == "" || e.Alert == o.Alert) &&
+		(len(o.States) == 0 || slices.Contains(o.States, e.State)) &&
+		(o.From.IsZero() || !e.Time.Before(o.From)) &&
+		(o.To.IsZero() || e.Time.Before(o.To))
+}
+
+// historyQueueSize is the number of entries waiting to be written to the
+// journal, entries are not persisted once it is full
+const historyQueueSize int = 1000
+
+// historyWrite is an entry to append to the journal, seq is its
+// position in the sequence of recorded entries
+type historyWrite struct {
+	seq   uint64
+	entry HistoryEntry
+}
+
+// History records alert state changes in an append-only journal,
+// a file of JSON lines. Entries older than the retention, relative to
+// the latest entry, are dropped. The journal is compacted on startup
+// and once it holds more dropped entries than kept ones.
+//
+// Record is called while alerts are evaluated so the journal is written
+// by another goroutine.
+type History struct {
+	mutex     sync.Mutex
+	path      string   // in memory only if empty
+	file      *os.File // journal opened in append mode, owned by the writer once opened
+	retention time.Duration
+	entries   []HistoryEntry // sorted by time
+	last      map[alert.NameT]alert.State
+	stale     int               // journal lines of dropped entries
+	seq       uint64            // number of entries recorded
+	writes    chan historyWrite // nil if the journal is not open
+	done      chan struct{}     // closed once the writer returns
+}
+
+// NewHistory creates an history, the journal is loaded by Open
+func NewHistory(path string, retention time.Duration) *History {
+	return &History{
+		path:      path,
+		retention: retention,
+		last:      make(map[alert.NameT]alert.State),
+	}
+}
+
+// Open loads the journal, creating it if needed. Lines which can't be
+// read, such as a line truncated by a crash, are skipped.
+func (o *History) Open() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.path == "" {
+		return nil
+	}
+
+	f, err := os.Open(o.path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var e HistoryEntry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				logrus.Warnf("alert history - %s - skipping invalid line - %s", o.path, err)
+				continue
+			}
+			o.append(e)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	if err := o.compact(o.entries); err != nil {
+		return err
+	}
+	o.stale = 0
+	o.writes = make(chan historyWrite, historyQueueSize)
+	o.done = make(chan struct{})
+	go o.writer(o.writes)
+	return nil
+}
+
+// Record appends t to the history if the alert's state changed
+func (o *History) Record(t AlertStateTransition) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	name, state := t.Alert.Name(), t.Alert.State()
+	if last, ok := o.last[name]; state == last || !ok && state == alert.Inactive {
+		// Alerts are published again when muted, or when first evaluated
+		return
+	}
+
+	e := HistoryEntry{
+		Time:        time.Unix(t.Time, 0).UTC(),
+		Alert:       name,
+		State:       state,
+		Prev:        t.Prev,
+		Silenced:    t.Silenced,
+		Inhibited:   t.Inhibited,
+		Description: t.Alert.Description(),
+	}
+	o.append(e)
+	if o.writes == nil {
+		return
+	}
+
+	o.seq++
+	select {
+	case o.writes <- historyWrite{o.seq, e}:
+	default:
+		logrus.Errorf("alert history - %s - journal queue is full, entry of %s not persisted", o.path, name)
+	}
+}
+
+// writer appends entries to the journal then compacts it once it holds
+// more dropped entries than kept ones, until writes is closed
+func (o *History) writer(writes <-chan historyWrite) {
+	defer close(o.done)
+
+	var compacted uint64 // entries up to it are in the compacted journal
+	for w := range writes {
+		if w.seq <= compacted || o.file == nil {
+			continue
+		}
+		if err := o.write(o.file, w.entry); err != nil {
+			logrus.Errorf("alert history - %s - %s", o.path, err)
+		}
+
+		o.mutex.Lock()
+		var entries []HistoryEntry
+		if o.stale > len(o.entries) {
+			entries = slices.Clone(o.entries)
+			compacted = o.seq
+			o.stale = 0
+		}
+		o.mutex.Unlock()
+
+		if entries == nil {
+			continue
+		}
+		if err := o.compact(entries); err != nil {
+			logrus.Errorf("alert history - %s - compaction failed - %s", o.path, err)
+		}
+	}
+}
+
+// append adds e to the entries then applies the retention
+func (o *History) append(e HistoryEntry) {
+	i := len(o.entries)
+	for i > 0 && o.entries[i-1].Time.After(e.Time) {
+		i--
+	}
+	o.entries = slices.Insert(o.entries, i, e)
+	o.last[e.Alert] = e.State
+
+	oldest := o.entries[len(o.entries)-1].Time.Add(-o.retention)
+	n := 0
+	for n < len(o.entries) && o.entries[n].Time.Before(oldest) {
+		n++
+	}
+	o.entries = o.entries[n:]
+	o.stale += n
+}
+
+// compact rewrites the journal with entries then
+// reopens it in append mode
+func (o *History) compact(entries []HistoryEntry) error {
+	if o.file != nil {
+		o.file.Close()
+		o.file = nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(o.path), filepath.Base(o.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	w := bufio.NewWriter(tmp)
+	for _, e := range entries {
+		if err := o.write(w, e); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), o.path); err != nil {
+		return err
+	}
+
+	o.file, err = os.OpenFile(o.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	return err
+}
+
+func (o *History) write(w io.Writer, e HistoryEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Query returns the entries selected by q, oldest first
+func (o *History) Query(q HistoryQuery) []HistoryEntry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	entries := []HistoryEntry{}
+	for _, e := range o.entries {
+		if q.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+	return entries
+}
+
+// Close writes the queued entries then closes the journal
+func (o *History) Close() error {
+	o.mutex.Lock()
+	writes := o.writes
+	o.writes = nil
+	o.mutex.Unlock()
+
+	if writes != nil {
+		close(writes)
+		<-o.done
+	}
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// stateAlert is an alert in a fixed state
+type stateAlert struct {
+	name  alert.NameT
+	state alert.State
+}
+
+func (o stateAlert) Name() alert.NameT               { return o.name }
+func (o stateAlert) State() alert.State              { return o.state }
+func (o stateAlert) Eval(metrics.Metric) alert.State { return o.state }
+func (o stateAlert) EvalTime() time.Time             { return time.Time{} }
+func (o stateAlert) Description() string             { return "" }
+func (o stateAlert) Labels() alert.Labels            { return alert.Labels{alert.AlertName: string(o.name)} }
+func (o stateAlert) DeepCopy() alert.Alert           { return o }
+
+func record(o *History, name alert.NameT, prev, state alert.State, unix int64) {
+	o.Record(AlertStateTransition{Prev: prev, Alert: stateAlert{name, state}, Time: unix})
+}
+
+func TestHistoryRecordsStateChanges(t *testing.T) {
+	o := NewHistory("", time.Hour)
+	record(o, "busy", alert.Inactive, alert.Inactive, 100) // first evaluation
+	record(o, "busy", alert.Inactive, alert.Pending, 110)
+	record(o, "busy", alert.Inactive, alert.Pending, 110) // published again
+	record(o, "busy", alert.Pending, alert.Active, 120)
+	record(o, "errors", alert.Inactive, alert.Pending, 130)
+	record(o, "busy", alert.Active, alert.Inactive, 140)
+
+	assert.Len(t, o.Query(HistoryQuery{}), 4)
+	active := o.Query(HistoryQuery{Alert: "busy", States: []alert.State{alert.Active, alert.Inactive}})
+	assert.Equal(t, []HistoryEntry{
+		{Time: time.Unix(120, 0).UTC(), Alert: "busy", State: alert.Active, Prev: alert.Pending},
+		{Time: time.Unix(140, 0).UTC(), Alert: "busy", State: alert.Inactive, Prev: alert.Active},
+	}, active)
+
+	q := HistoryQuery{From: time.Unix(110, 0), To: time.Unix(130, 0), Limit: 1}
+	assert.Equal(t, time.Unix(120, 0).UTC(), o.Query(q)[0].Time)
+
+	// Retention is relative to the latest entry
+	record(o, "errors", alert.Pending, alert.Active, 3730)
+	assert.Equal(t, []time.Time{time.Unix(130, 0).UTC(), time.Unix(140, 0).UTC(), time.Unix(3730, 0).UTC()}, times(o.Query(HistoryQuery{})))
+}
+
+func TestHistoryIsReloaded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	o := NewHistory(path, time.Hour)
+	assert.NoError(t, o.Open())
+	record(o, "busy", alert.Inactive, alert.Pending, 100)
+	record(o, "busy", alert.Pending, alert.Active, 110)
+	assert.NoError(t, o.Close())
+
+	// A line truncated by a crash is skipped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	assert.NoError(t, err)
+	f.WriteString(`{"time": "1970-01-01T00:02:00Z", "alert": "bu`)
+	f.Close()
+
+	o = NewHistory(path, time.Hour)
+	assert.NoError(t, o.Open())
+	assert.Equal(t, []time.Time{time.Unix(100, 0).UTC(), time.Unix(110, 0).UTC()}, times(o.Query(HistoryQuery{})))
+
+	// Unchanged states are not recorded again
+	record(o, "busy", alert.Active, alert.Active, 120)
+	record(o, "busy", alert.Active, alert.Inactive, 130)
+	assert.NoError(t, o.Close())
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(data)), "\n"), 3)
+}
+
+func times(entries []HistoryEntry) []time.Time {
+	t := make([]time.Time, 0, len(entries))
+	for _, e := range entries {
+		t = append(t, e.Time)
+	}
+	return t
+}
+
+func TestHistoryCompactsJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	lines := func() int {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		return len(strings.Split(strings.TrimSpace(string(data)), "\n"))
+	}
+
+	o := NewHistory(path, 30*time.Second)
+	assert.NoError(t, o.Open())
+	for unix := int64(100); unix < 200; unix += 10 {
+		record(o, "busy", alert.Inactive, alert.Pending, unix)
+		record(o, "busy", alert.Pending, alert.Inactive, unix+5)
+	}
+	assert.NoError(t, o.Close())
+	assert.Less(t, lines(), 20)
+
+	// Entries of [165, 195] are kept
+	o = NewHistory(path, 30*time.Second)
+	assert.NoError(t, o.Open())
+	defer o.Close()
+	assert.Len(t, o.Query(HistoryQuery{}), 7)
+	assert.Equal(t, 7, lines())
+}
@@ -1,29 +1,78 @@
 package backend
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/config"
 	"github.com/julnicolas/httpmon/pkg/parser"
 	"github.com/julnicolas/httpmon/pkg/reader"
 	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/sirupsen/logrus"
 )
 
 // Ingestor is an object able to ingest Traces from
 // various configurable raw formats
 type Ingestor struct {
 	reader reader.Reader // reads the input stream
-	parser parser.Parser // parses incomming data
 	traces chan trace.Trace
 	source string // Ingestion source
-	// implement filters
+	// filter drops traces before they are polled, it can be swapped at runtime
+	filter atomic.Pointer[Filter]
+
+	mutex      sync.Mutex
+	parser     parser.Parser // parses incomming data, nil until detected
+	parserName string
+	detector   *detector // nil if the parser is configured
+	// parseErrors counts lines which could not be parsed, they are skipped
+	parseErrors atomic.Uint64
+	filtered    atomic.Uint64 // traces dropped by the filter
+}
+
+// detectIdle is how long the stream is idle before the format is
+// detected from an incomplete sample
+const detectIdle = 2 * time.Second
+
+// detector samples the first lines of the stream to detect its format
+type detector struct {
+	opts    parser.Options
+	sample  []string
+	size    int
+	verbose bool // logs the detected format, shown by the terminal UI otherwise
 }
 
 // Creates a new ingestor
-func NewIngestor(source string, r reader.Reader, p parser.Parser, bufferLen uint) *Ingestor {
-	return &Ingestor{
+func NewIngestor(source string, r reader.Reader, name string, p parser.Parser, bufferLen uint) *Ingestor {
+	o := &Ingestor{
+		reader:     r,
+		parser:     p,
+		parserName: name,
+		traces:     make(chan trace.Trace, bufferLen),
+		source:     source,
+	}
+	o.filter.Store(NewFilter(config.Filter{}))
+	return o
+}
+
+// NewDetectingIngestor creates a new ingestor which detects the stream's
+// format from its first sampleLen lines, or from the lines read before the
+// stream went idle. The parser with the best success ratio among registered
+// parsers is then used for the whole stream. verbose logs the detected format.
+func NewDetectingIngestor(source string, r reader.Reader, opts parser.Options, sampleLen uint, bufferLen uint, verbose bool) *Ingestor {
+	o := &Ingestor{
 		reader: r,
-		parser: p,
+		detector: &detector{
+			opts:    opts,
+			sample:  make([]string, 0, sampleLen),
+			size:    int(sampleLen),
+			verbose: verbose,
+		},
 		traces: make(chan trace.Trace, bufferLen),
 		source: source,
 	}
+	o.filter.Store(NewFilter(config.Filter{}))
+	return o
 }
 
 func (o *Ingestor) Init() error {
@@ -35,27 +84,146 @@ func (o *Ingestor) Init() error {
 }
 
 func (o *Ingestor) Ingest() error {
+	if o.detector != nil {
+		raw, ok, err := o.reader.ReadTimeout(detectIdle)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return o.detectIdle()
+		}
+		return o.detect(raw)
+	}
+
 	raw, err := o.reader.Read()
 	if err != nil {
 		return err
 	}
 
+	o.parse(raw)
+	return nil
+}
+
+// parse parses a raw line then sends the trace for polling.
+// Lines which cannot be parsed are counted then skipped.
+func (o *Ingestor) parse(raw string) {
 	trace, err := o.parser.Parse(raw)
 	if err == parser.ErrHeaderData {
+		return
+	}
+	if err != nil {
+		o.parseErrors.Add(1)
+		return
+	}
+	if !o.filter.Load().Keep(trace) {
+		o.filtered.Add(1)
+		return
+	}
+
+	o.traces <- trace
+}
+
+// detect samples raw until the sample is complete, then locks in
+// the best parser and replays the sample through it. A sample no
+// parser matches is dropped and sampling starts over.
+func (o *Ingestor) detect(raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	d := o.detector
+	d.sample = append(d.sample, raw)
+	if len(d.sample) < d.size {
+		return nil
+	}
+
+	score, err := parser.Detect(d.sample, d.opts)
+	if err != nil {
+		// Ingestion goes on, the format may be detected from the next
+		// lines. Dropped lines are counted as parse errors.
+		logrus.Warnf("%s, %d sampled lines dropped", err, len(d.sample))
+		o.parseErrors.Add(uint64(len(d.sample)))
+		d.sample = d.sample[:0]
 		return nil
 	}
+	return o.lockIn(score)
+}
+
+// detectIdle detects the format from the lines sampled so far, a short
+// file or a quiet log may never fill the sample. Sampling goes on if
+// no parser matches them.
+func (o *Ingestor) detectIdle() error {
+	d := o.detector
+	if len(d.sample) == 0 {
+		return nil
+	}
+
+	score, err := parser.Detect(d.sample, d.opts)
+	if err != nil {
+		return nil
+	}
+	return o.lockIn(score)
+}
+
+// lockIn uses the parser of score for the whole stream
+// then replays the sample through it
+func (o *Ingestor) lockIn(score parser.Score) error {
+	d := o.detector
+	p, err := parser.New(score.Name, d.opts)
 	if err != nil {
 		return err
 	}
 
-	o.traces <- trace
-	return err
+	if d.verbose {
+		logrus.Infof("detected log format %s, %.0f%% of %d sampled lines parsed", score.Name, score.Ratio*100, len(d.sample))
+	}
+
+	o.mutex.Lock()
+	o.parser = p
+	o.parserName = score.Name
+	o.mutex.Unlock()
+
+	o.detector = nil
+	for _, line := range d.sample {
+		o.parse(line)
+	}
+
+	return nil
 }
 
 func (o *Ingestor) Poll() trace.Trace {
 	return <-o.traces
 }
 
+// Traces exposes parsed traces, in read order
+func (o *Ingestor) Traces() <-chan trace.Trace {
+	return o.traces
+}
+
+// Parser returns the name of the parser in use,
+// empty while the format is being detected
+func (o *Ingestor) Parser() string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.parserName
+}
+
+// SetFilter swaps the filter, it is thread-safe
+func (o *Ingestor) SetFilter(f *Filter) {
+	o.filter.Store(f)
+}
+
+// Filtered returns the number of traces dropped by the filter
+func (o *Ingestor) Filtered() uint64 {
+	return o.filtered.Load()
+}
+
+// ParseErrors returns the number of lines which could not be parsed
+func (o *Ingestor) ParseErrors() uint64 {
+	return o.parseErrors.Load()
+}
+
 func (o *Ingestor) Close() error {
 	return o.reader.Close()
 }
@@ -0,0 +1,84 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeReader reads lines then is idle, or fails with err if set
+type fakeReader struct {
+	lines []string
+	err   error
+}
+
+func (o *fakeReader) Open(string) error { return nil }
+func (o *fakeReader) Close() error      { return nil }
+func (o *fakeReader) Buffered() (int, int) {
+	return len(o.lines), len(o.lines)
+}
+
+func (o *fakeReader) Read() (string, error) {
+	line, _, err := o.ReadTimeout(0)
+	return line, err
+}
+
+func (o *fakeReader) ReadTimeout(time.Duration) (string, bool, error) {
+	if len(o.lines) == 0 {
+		return "", false, o.err
+	}
+	line := o.lines[0]
+	o.lines = o.lines[1:]
+	return line, true, nil
+}
+
+func TestDetectingIngestorDetectsOnIdle(t *testing.T) {
+	r := &fakeReader{lines: []string{
+		`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /api/user HTTP/1.0" 200 2326`,
+		`127.0.0.1 - frank [10/Oct/2000:13:55:37 -0700] "GET /api/user HTTP/1.0" 200 2326`,
+	}}
+	o := NewDetectingIngestor("access.log", r, parser.DefaultOptions(), 10, 10, false)
+
+	// The sample is incomplete
+	assert.NoError(t, o.Ingest())
+	assert.NoError(t, o.Ingest())
+	assert.Equal(t, "", o.Parser())
+	assert.Len(t, o.Traces(), 0)
+
+	// The stream is idle
+	assert.NoError(t, o.Ingest())
+	assert.Equal(t, "clf", o.Parser())
+	assert.Len(t, o.Traces(), 2)
+}
+
+func TestDetectingIngestorKeepsSamplingUnknownFormats(t *testing.T) {
+	r := &fakeReader{lines: []string{"not a log line"}}
+	o := NewDetectingIngestor("access.log", r, parser.DefaultOptions(), 10, 10, false)
+
+	assert.NoError(t, o.Ingest())
+	assert.NoError(t, o.Ingest())
+	assert.Equal(t, "", o.Parser())
+}
+
+func TestDetectingIngestorDropsUnknownSamples(t *testing.T) {
+	r := &fakeReader{lines: []string{
+		"not a log line",
+		"neither is this one",
+		`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /api/user HTTP/1.0" 200 2326`,
+		`127.0.0.1 - frank [10/Oct/2000:13:55:37 -0700] "GET /api/user HTTP/1.0" 200 2326`,
+	}}
+	o := NewDetectingIngestor("access.log", r, parser.DefaultOptions(), 2, 10, false)
+
+	// The first sample matches no parser, it is dropped
+	assert.NoError(t, o.Ingest())
+	assert.NoError(t, o.Ingest())
+	assert.Equal(t, "", o.Parser())
+	assert.Equal(t, uint64(2), o.ParseErrors())
+
+	assert.NoError(t, o.Ingest())
+	assert.NoError(t, o.Ingest())
+	assert.Equal(t, "clf", o.Parser())
+	assert.Len(t, o.Traces(), 2)
+}
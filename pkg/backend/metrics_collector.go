@@ -2,6 +2,7 @@ package backend
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/julnicolas/httpmon/pkg/metrics"
 	"github.com/julnicolas/httpmon/pkg/trace"
@@ -35,6 +36,16 @@ func (o *MetricsCollector) Collect(t trace.Trace) error {
 	return nil
 }
 
+// Advance closes the periods ended before now of probers computing
+// metrics over time periods, see metrics.Advancer
+func (o *MetricsCollector) Advance(now time.Time) {
+	for _, p := range o.probers {
+		if a, ok := p.(metrics.Advancer); ok {
+			a.Advance(now)
+		}
+	}
+}
+
 // DeepCopy returns a deep copy of the metric struct.
 // It is thread-safe, more expensive as locking non atomic structures
 // on top of deep-copying them
@@ -70,3 +81,12 @@ func (o *MetricsCollector) lookupMetric(name string) (metrics.Prober, error) {
 	}
 	return m, nil
 }
+
+// Cardinality returns the number of label values held per prober
+func (o *MetricsCollector) Cardinality() map[string]int {
+	c := make(map[string]int, len(o.probers))
+	for name, p := range o.probers {
+		c[name] = p.Cardinality()
+	}
+	return c
+}
@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+)
+
+// ReorderBuffer holds traces for up to a lateness duration, in event
+// time, so that traces received out of order are emitted sorted by date.
+//
+// The watermark is the most recent date received minus the lateness.
+// Traces dated before the watermark are emitted, traces received after
+// the watermark passed their date are too late: they are dropped and counted.
+//
+// A zero lateness disables the buffer, traces are emitted as received.
+type ReorderBuffer struct {
+	mutex     sync.Mutex
+	lateness  time.Duration
+	traces    traceHeap
+	maxDate   time.Time // most recent date received
+	watermark time.Time // traces up to the watermark have been emitted
+	seq       uint64    // receipt order, keeps equal dates in order
+	dropped   atomic.Uint64
+}
+
+func NewReorderBuffer(lateness time.Duration) *ReorderBuffer {
+	return &ReorderBuffer{
+		lateness: lateness,
+	}
+}
+
+// Push buffers t then returns the traces which passed the watermark,
+// sorted by date
+func (o *ReorderBuffer) Push(t trace.Trace) []trace.Trace {
+	if o.lateness <= 0 {
+		return []trace.Trace{t}
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if t.Date.Before(o.watermark) {
+		o.dropped.Add(1)
+		return nil
+	}
+
+	heap.Push(&o.traces, sequencedTrace{Trace: t, seq: o.seq})
+	o.seq++
+
+	if t.Date.After(o.maxDate) {
+		o.maxDate = t.Date
+		o.watermark = o.maxDate.Add(-o.lateness)
+	}
+
+	return o.pop(o.watermark)
+}
+
+// Flush emits all buffered traces, sorted by date. It is meant to be called
+// when the stream is idle. The watermark does not move: traces received
+// after a pause are still accepted within lateness, they may then be
+// emitted after more recent flushed ones.
+func (o *ReorderBuffer) Flush() []trace.Trace {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.pop(o.maxDate)
+}
+
+// pop removes then returns buffered traces dated up to watermark included
+func (o *ReorderBuffer) pop(watermark time.Time) []trace.Trace {
+	var ready []trace.Trace
+	for o.traces.Len() > 0 && !o.traces[0].Date.After(watermark) {
+		ready = append(ready, heap.Pop(&o.traces).(sequencedTrace).Trace)
+	}
+	return ready
+}
+
+// Len returns the number of buffered traces
+func (o *ReorderBuffer) Len() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.traces.Len()
+}
+
+// Dropped returns the number of traces dropped because they were too late
+func (o *ReorderBuffer) Dropped() uint64 {
+	return o.dropped.Load()
+}
+
+// sequencedTrace is a trace along with its receipt order
+type sequencedTrace struct {
+	trace.Trace
+	seq uint64
+}
+
+// traceHeap is a min-heap of traces ordered by date then receipt order
+type traceHeap []sequencedTrace
+
+func (o traceHeap) Len() int { return len(o) }
+func (o traceHeap) Less(i, j int) bool {
+	if o[i].Date.Equal(o[j].Date) {
+		return o[i].seq < o[j].seq
+	}
+	return o[i].Date.Before(o[j].Date)
+}
+func (o traceHeap) Swap(i, j int) { o[i], o[j] = o[j], o[i] }
+
+func (o *traceHeap) Push(x any) { *o = append(*o, x.(sequencedTrace)) }
+func (o *traceHeap) Pop() any {
+	old := *o
+	n := len(old)
+	x := old[n-1]
+	*o = old[:n-1]
+	return x
+}
+
+// state returns a snapshot of the buffer for debugging
+func (o *ReorderBuffer) state() ReorderState {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return ReorderState{
+		Lateness:  o.lateness.String(),
+		Len:       o.traces.Len(),
+		Dropped:   o.dropped.Load(),
+		Watermark: o.watermark,
+	}
+}
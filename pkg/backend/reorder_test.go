@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func traceAt(unix int64, section string) trace.Trace {
+	return trace.Trace{Date: time.Unix(unix, 0), Section: section}
+}
+
+func dates(traces []trace.Trace) []int64 {
+	d := make([]int64, 0, len(traces))
+	for _, t := range traces {
+		d = append(d, t.Date.Unix())
+	}
+	return d
+}
+
+func TestReorderBufferEmitsTracesInOrderOnceWatermarkPassed(t *testing.T) {
+	b := NewReorderBuffer(5 * time.Second)
+
+	r1 := b.Push(traceAt(100, "/"))
+	r2 := b.Push(traceAt(103, "/"))
+	r3 := b.Push(traceAt(101, "/"))
+	r4 := b.Push(traceAt(107, "/")) // watermark 102
+	r5 := b.Push(traceAt(110, "/")) // watermark 105
+
+	assert.Empty(t, r1)
+	assert.Empty(t, r2)
+	assert.Empty(t, r3)
+	assert.Equal(t, []int64{100, 101}, dates(r4))
+	assert.Equal(t, []int64{103}, dates(r5))
+	assert.Equal(t, 2, b.Len())
+	assert.Equal(t, []int64{107, 110}, dates(b.Flush()))
+}
+
+func TestReorderBufferDropsTracesOlderThanWatermark(t *testing.T) {
+	b := NewReorderBuffer(5 * time.Second)
+
+	b.Push(traceAt(100, "/"))
+	b.Push(traceAt(110, "/")) // watermark 105
+	late := b.Push(traceAt(104, "/"))
+	onTime := b.Push(traceAt(105, "/"))
+
+	assert.Empty(t, late)
+	assert.Equal(t, []int64{105}, dates(onTime))
+	assert.Equal(t, uint64(1), b.Dropped())
+	assert.Equal(t, []int64{110}, dates(b.Flush()))
+}
+
+func TestReorderBufferFlushKeepsWatermark(t *testing.T) {
+	b := NewReorderBuffer(5 * time.Second)
+
+	b.Push(traceAt(100, "/"))
+	b.Push(traceAt(110, "/")) // watermark 105
+	assert.Equal(t, []int64{110}, dates(b.Flush()))
+
+	// Still within lateness after the pause
+	assert.Empty(t, b.Push(traceAt(106, "/")))
+	assert.Empty(t, b.Push(traceAt(104, "/")))
+	assert.Equal(t, uint64(1), b.Dropped())
+	assert.Equal(t, []int64{106}, dates(b.Flush()))
+}
+
+func TestReorderBufferIsDisabledWithoutLateness(t *testing.T) {
+	b := NewReorderBuffer(0)
+
+	r1 := b.Push(traceAt(110, "/"))
+	r2 := b.Push(traceAt(100, "/"))
+
+	assert.Equal(t, []int64{110}, dates(r1))
+	assert.Equal(t, []int64{100}, dates(r2))
+	assert.Equal(t, uint64(0), b.Dropped())
+}
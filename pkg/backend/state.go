@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"time"
+)
+
+// State is a snapshot of the backend internals, to diagnose a stuck
+// or memory-hungry instance
+type State struct {
+	Parser      string `json:"parser"`
+	Ready       bool   `json:"ready"`
+	ParseErrors uint64 `json:"parse_errors"`
+	Filtered    uint64 `json:"filtered"` // traces dropped by filters
+	RunErr      string `json:"run_error,omitempty"`
+	// ReadBuffer holds raw lines waiting to be parsed
+	ReadBuffer BufferState `json:"read_buffer"`
+	// TraceBuffer holds parsed traces waiting to be processed
+	TraceBuffer BufferState  `json:"trace_buffer"`
+	Reorder     ReorderState `json:"reorder_buffer"`
+	// Cardinality is the number of label values held per prober
+	Cardinality map[string]int `json:"cardinality"`
+	Alerts      []AlertState   `json:"alerts"`
+}
+
+// BufferState is the fill level of a bounded buffer
+type BufferState struct {
+	Len int `json:"len"`
+	Cap int `json:"cap"`
+}
+
+type ReorderState struct {
+	Lateness  string    `json:"lateness"`
+	Len       int       `json:"len"` // traces held
+	Dropped   uint64    `json:"dropped"`
+	Watermark time.Time `json:"watermark"`
+}
+
+// AlertState is the alert manager's internal state of an alert
+type AlertState struct {
+	Name          string    `json:"name"`
+	Metric        string    `json:"metric"`
+	State         string    `json:"state"`
+	Prev          string    `json:"prev"`
+	EvalTime      time.Time `json:"eval_time"`
+	PublishedOnce bool      `json:"published_once"`
+	Silenced      bool      `json:"silenced"`
+	Inhibited     bool      `json:"inhibited"`
+	Description   string    `json:"description"`
+}
+
+// State returns a snapshot of the backend internals, it is thread-safe
+func (o *Backend) State() State {
+	s := State{
+		Parser:      o.Parser(),
+		Ready:       o.Ready(),
+		ParseErrors: o.ParseErrors(),
+		Filtered:    o.ingestor.Filtered(),
+		TraceBuffer: BufferState{Len: len(o.ingestor.traces), Cap: cap(o.ingestor.traces)},
+		Reorder:     o.reorder.state(),
+		Cardinality: o.collector.Cardinality(),
+		Alerts:      o.alertor.state(),
+	}
+	s.ReadBuffer.Len, s.ReadBuffer.Cap = o.ingestor.reader.Buffered()
+	if err := o.RunErr(); err != nil {
+		s.RunErr = err.Error()
+	}
+
+	return s
+}
@@ -1,28 +1,183 @@
 package config
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+)
 
 type Alert struct {
 	// Period is the alert loop evaluation period
-	// -> alerts are evaluated after every Period time
-	Period            time.Duration
-	RequestsPerSecond RequestsPerSecond
+	// -> alerts are evaluated after every Period time, in log time
+	// while replaying logs and in real time while tailing them
+	Period            time.Duration     `json:"period" yaml:"period"`
+	RequestsPerSecond RequestsPerSecond `json:"requests_per_second" yaml:"requests_per_second"`
+	// Rules are alerts declared over any metric
+	Rules []Rule `json:"rules" yaml:"rules"`
+	// ErrorRatios are alerts on the ratio of error status classes
+	ErrorRatios []ErrorRatio `json:"error_ratios" yaml:"error_ratios"`
+	// Absent are alerts on traffic which stopped
+	Absent []Absent `json:"absent" yaml:"absent"`
+	// Silences mute alerts' notifications for a while
+	Silences []Silence `json:"silences" yaml:"silences"`
+	// Inhibitions mute alerts while others are active
+	Inhibitions []Inhibition `json:"inhibitions" yaml:"inhibitions"`
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o Alert) MarshalJSON() ([]byte, error) {
+	type alias Alert
+	return json.Marshal(struct {
+		alias
+		Period string `json:"period"`
+	}{alias(o), o.Period.String()})
 }
 
 func (o Alert) Default() Alert {
 	return Alert{
 		Period:            time.Second,
 		RequestsPerSecond: RequestsPerSecond{}.Default(),
+		Rules:             []Rule{},
+		ErrorRatios:       []ErrorRatio{},
+		Absent:            []Absent{},
+		Silences:          []Silence{},
+		Inhibitions:       []Inhibition{},
 	}
 }
 
+// validate checks rules, errors are fieldErrors
+func (o Alert) validate() error {
+	names := map[string]bool{string(alert.ReqPerS): true}
+	for i, r := range o.Rules {
+		key := fmt.Sprintf("alert.rules.%d", i)
+		checks := []fieldError{
+			{key + ".name", checkRuleName(r.Name, names)},
+			{key + ".metric", alert.CheckMetric(r.Metric)},
+			{key + ".selector", alert.CheckSelector(r.Metric, r.Selector)},
+			{key + ".by", alert.CheckBy(r.Metric, r.By, r.Selector)},
+			{key + ".aggregation", alert.CheckAggregation(r.aggregation(), r.Points)},
+			{key + ".operator", alert.CheckOperator(alert.Operator(r.Operator))},
+			{key + ".for", checkLateness(r.For)},
+		}
+		checks = append(checks, r.Resolve.checks(key+".resolve", nil)...)
+
+		for _, c := range checks {
+			if c.err != nil {
+				return c
+			}
+		}
+		names[r.Name] = true
+	}
+
+	for i, r := range o.ErrorRatios {
+		key := fmt.Sprintf("alert.error_ratios.%d", i)
+		checks := []fieldError{
+			{key + ".name", checkRuleName(r.Name, names)},
+			{key + ".classes", alert.CheckClasses(r.Classes)},
+			{key + ".window", checkPeriod(r.Window)},
+			{key + ".threshold", checkRatio(r.Threshold)},
+			{key + ".for", checkLateness(r.For)},
+		}
+		checks = append(checks, r.Resolve.checks(key+".resolve", checkRatio)...)
+
+		for _, c := range checks {
+			if c.err != nil {
+				return c
+			}
+		}
+		names[r.Name] = true
+	}
+
+	for i, a := range o.Absent {
+		key := fmt.Sprintf("alert.absent.%d", i)
+		checks := []fieldError{
+			{key + ".name", checkRuleName(a.Name, names)},
+			{key + ".host", alert.CheckAbsentTraffic(a.Section, a.Host)},
+			{key + ".after", checkPeriod(a.After)},
+			{key + ".for", checkLateness(a.For)},
+		}
+		for _, c := range checks {
+			if c.err != nil {
+				return c
+			}
+		}
+		names[a.Name] = true
+	}
+
+	for i, s := range o.Silences {
+		key := fmt.Sprintf("alert.silences.%d", i)
+		checks := []fieldError{
+			{key + ".matchers", alert.CheckMatchers(s.Matchers)},
+			{key + ".ends_at", checkSilenceEnd(s.StartsAt, s.EndsAt)},
+		}
+		for _, c := range checks {
+			if c.err != nil {
+				return c
+			}
+		}
+	}
+
+	for i, in := range o.Inhibitions {
+		key := fmt.Sprintf("alert.inhibitions.%d", i)
+		checks := []fieldError{
+			{key + ".source", alert.CheckMatchers(in.Source)},
+			{key + ".target", alert.CheckMatchers(in.Target)},
+		}
+		for _, c := range checks {
+			if c.err != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+func checkSilenceEnd(start, end time.Time) error {
+	if end.IsZero() {
+		return fmt.Errorf("silences must end")
+	}
+	if !end.After(start) {
+		return fmt.Errorf("must be after starts_at, received %s", end.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func checkRatio(r float64) error {
+	if r < 0 || r > 1 {
+		return fmt.Errorf("must be in [0, 1], received %g", r)
+	}
+	return nil
+}
+
+func checkRuleName(name string, taken map[string]bool) error {
+	if name == "" {
+		return fmt.Errorf("rules must have a name")
+	}
+	if taken[name] {
+		return fmt.Errorf("alert names must be unique, %q is already used", name)
+	}
+	return nil
+}
+
 // RequestPerSecond is struct to configure the RequestsPerSecond alert
 type RequestsPerSecond struct {
 	// Period of time over which the alert rule must be true so that the alert
 	// becomes active
-	Period time.Duration
+	Period time.Duration `json:"period" yaml:"period"`
 	// Threshold of requests per second, if greater the alert is on
-	Threshold float64
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	Resolve   Resolve `json:"resolve" yaml:"resolve"`
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o RequestsPerSecond) MarshalJSON() ([]byte, error) {
+	type alias RequestsPerSecond
+	return json.Marshal(struct {
+		alias
+		Period string `json:"period"`
+	}{alias(o), o.Period.String()})
 }
 
 func (o RequestsPerSecond) Default() RequestsPerSecond {
@@ -40,3 +195,220 @@ func (o RequestsPerSecond) Default() RequestsPerSecond {
 		Threshold: 10,
 	}
 }
+
+// Rule declares an alert over any metric, see alert.RuleInput
+type Rule struct {
+	Name string `json:"name" yaml:"name"`
+	// Metric is a metric name such as ReqsPerSecond or Latency
+	Metric string `json:"metric" yaml:"metric"`
+	// Selector selects a series by labels: section, status, host or quantile
+	// depending on the metric. The metric's total is used if empty.
+	Selector map[string]string `json:"selector" yaml:"selector"`
+	// By is a label the rule is evaluated for, one alert per value,
+	// such as section
+	By string `json:"by" yaml:"by"`
+	// Aggregation of the last Points points, last (default), avg or sum
+	Aggregation string `json:"aggregation" yaml:"aggregation"`
+	Points      int    `json:"points" yaml:"points"`
+	// Operator compares the aggregated value to Threshold: >, >=, <, <=, == or !=
+	Operator  string  `json:"operator" yaml:"operator"`
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	// For is how long the comparison must hold before the alert is active
+	For     time.Duration `json:"for" yaml:"for"`
+	Resolve Resolve       `json:"resolve" yaml:"resolve"`
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o Rule) MarshalJSON() ([]byte, error) {
+	type alias Rule
+	return json.Marshal(struct {
+		alias
+		For string `json:"for"`
+	}{alias(o), o.For.String()})
+}
+
+// Input returns the rule's alert configuration
+func (o Rule) Input() alert.RuleInput {
+	return alert.RuleInput{
+		Name:        alert.NameT(o.Name),
+		Metric:      o.Metric,
+		Selector:    o.Selector,
+		By:          o.By,
+		Aggregation: o.aggregation(),
+		Points:      o.Points,
+		Operator:    alert.Operator(o.Operator),
+		Threshold:   o.Threshold,
+		For:         o.For,
+		Resolve:     o.Resolve.Input(),
+	}
+}
+
+func (o Rule) aggregation() alert.Aggregation {
+	if o.Aggregation == "" {
+		return alert.Last
+	}
+	return alert.Aggregation(o.Aggregation)
+}
+
+// ErrorRatio configures an alert on the ratio of requests of some status
+// classes to all requests, see alert.ErrorRatioInput
+type ErrorRatio struct {
+	Name string `json:"name" yaml:"name"`
+	// Classes are the status classes counted as errors, such as 5xx
+	Classes []string `json:"classes" yaml:"classes"`
+	// Section restricts the ratio to a section, all sections if empty
+	Section string `json:"section" yaml:"section"`
+	// Window is the time the ratio is computed over
+	Window time.Duration `json:"window" yaml:"window"`
+	// Threshold is a ratio in [0, 1], 0.05 for 5%
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	// MinRequests is the number of requests in Window under which
+	// the alert stays inactive
+	MinRequests uint `json:"min_requests" yaml:"min_requests"`
+	// For is how long the ratio must be over Threshold before the alert is active
+	For     time.Duration `json:"for" yaml:"for"`
+	Resolve Resolve       `json:"resolve" yaml:"resolve"`
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o ErrorRatio) MarshalJSON() ([]byte, error) {
+	type alias ErrorRatio
+	return json.Marshal(struct {
+		alias
+		Window string `json:"window"`
+		For    string `json:"for"`
+	}{alias(o), o.Window.String(), o.For.String()})
+}
+
+// Input returns the alert's configuration
+func (o ErrorRatio) Input() alert.ErrorRatioInput {
+	return alert.ErrorRatioInput{
+		Name:        alert.NameT(o.Name),
+		Classes:     o.Classes,
+		Section:     o.Section,
+		Window:      o.Window,
+		Threshold:   o.Threshold,
+		MinRequests: o.MinRequests,
+		For:         o.For,
+		Resolve:     o.Resolve.Input(),
+	}
+}
+
+// Absent configures an alert on traffic which stopped, see alert.AbsentInput
+type Absent struct {
+	Name string `json:"name" yaml:"name"`
+	// Section or Host restricts the alert to a section or a host,
+	// all traffic if both are empty
+	Section string `json:"section" yaml:"section"`
+	Host    string `json:"host" yaml:"host"`
+	// After is how long without any request before the alert is pending
+	After time.Duration `json:"after" yaml:"after"`
+	// For is how long the alert is pending before it is active
+	For time.Duration `json:"for" yaml:"for"`
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o Absent) MarshalJSON() ([]byte, error) {
+	type alias Absent
+	return json.Marshal(struct {
+		alias
+		After string `json:"after"`
+		For   string `json:"for"`
+	}{alias(o), o.After.String(), o.For.String()})
+}
+
+// Input returns the alert's configuration
+func (o Absent) Input() alert.AbsentInput {
+	return alert.AbsentInput{
+		Name:    alert.NameT(o.Name),
+		Section: o.Section,
+		Host:    o.Host,
+		After:   o.After,
+		For:     o.For,
+	}
+}
+
+// Resolve configures how active alerts resolve, by default on the first
+// evaluation their condition is false
+type Resolve struct {
+	// Threshold is a recovery threshold: an alert firing at >= 10 req/s
+	// with a recovery threshold of 7 resolves under 7 req/s.
+	// The alert's threshold if nil.
+	Threshold *float64 `json:"threshold" yaml:"threshold"`
+	// KeepFiringFor is how long the alert keeps firing once recovered
+	KeepFiringFor time.Duration `json:"keep_firing_for" yaml:"keep_firing_for"`
+	// MinDuration is the minimum time an alert fires before it resolves
+	MinDuration time.Duration `json:"min_duration" yaml:"min_duration"`
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o Resolve) MarshalJSON() ([]byte, error) {
+	type alias Resolve
+	return json.Marshal(struct {
+		alias
+		KeepFiringFor string `json:"keep_firing_for"`
+		MinDuration   string `json:"min_duration"`
+	}{alias(o), o.KeepFiringFor.String(), o.MinDuration.String()})
+}
+
+// Input returns the alerts' resolution configuration
+func (o Resolve) Input() alert.ResolveInput {
+	in := alert.ResolveInput{
+		KeepFiringFor: o.KeepFiringFor,
+		MinDuration:   o.MinDuration,
+	}
+	if o.Threshold != nil {
+		in.Recovery, in.HasRecovery = *o.Threshold, true
+	}
+	return in
+}
+
+// checks returns the checks of o at key, checkThreshold checks
+// the recovery threshold like the alert's one if not nil
+func (o Resolve) checks(key string, checkThreshold func(float64) error) []fieldError {
+	checks := []fieldError{
+		{key + ".keep_firing_for", checkLateness(o.KeepFiringFor)},
+		{key + ".min_duration", checkLateness(o.MinDuration)},
+	}
+	if o.Threshold != nil && checkThreshold != nil {
+		checks = append(checks, fieldError{key + ".threshold", checkThreshold(*o.Threshold)})
+	}
+	return checks
+}
+
+// Silence mutes the notifications of the alerts it matches
+// from StartsAt to EndsAt
+type Silence struct {
+	// Matchers map labels to regular expressions matching whole values,
+	// alertname is the name of the alert's rule
+	Matchers map[string]string `json:"matchers" yaml:"matchers"`
+	// StartsAt is when the silence starts, right away if empty
+	StartsAt time.Time `json:"starts_at" yaml:"starts_at"`
+	EndsAt   time.Time `json:"ends_at" yaml:"ends_at"`
+	Comment  string    `json:"comment" yaml:"comment"`
+}
+
+// Input returns the silence, o must have been validated
+func (o Silence) Input(id string) alert.Silence {
+	s, err := alert.NewSilence(o.Matchers, o.StartsAt, o.EndsAt, o.Comment, alert.CreatedByConfig)
+	if err != nil {
+		// Should never happen, silences are checked with configuration
+		panic(fmt.Errorf("configuration error - silence %s - %s", id, err))
+	}
+	s.ID = id
+	return s
+}
+
+// Inhibition mutes the alerts matched by Target while an alert matched
+// by Source is active, see Silence for matchers
+type Inhibition struct {
+	Source map[string]string `json:"source" yaml:"source"`
+	Target map[string]string `json:"target" yaml:"target"`
+	// Equal are labels which must have the same value in both alerts
+	Equal []string `json:"equal" yaml:"equal"`
+}
+
+// Input returns the inhibition, o must have been validated
+func (o Inhibition) Input() alert.Inhibition {
+	return alert.NewInhibition(o.Source, o.Target, o.Equal)
+}
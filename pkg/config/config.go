@@ -1,55 +1,202 @@
 package config
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/julnicolas/httpmon/pkg/parser"
 )
 
 type Config struct {
-	Debug          bool // Debug flag, waits a few seconds before starting
-	Period         time.Duration
-	File           string // file name or "stdin"
-	ReadBufferSize uint
-	Alert          Alert
+	Debug          bool          `json:"debug" yaml:"debug"` // Debug flag, waits a few seconds before starting
+	Period         time.Duration `json:"period" yaml:"period"`
+	Retention      uint          `json:"retention" yaml:"retention"` // number of periods kept in memory per time series
+	Lateness       time.Duration `json:"lateness" yaml:"lateness"`   // how long out of order traces are waited for
+	File           string        `json:"file" yaml:"file"`           // file name or "stdin"
+	ReadBufferSize uint          `json:"read_buffer_size" yaml:"read_buffer_size"`
+	Parser         Parser        `json:"parser" yaml:"parser"`
+	Filter         Filter        `json:"filter" yaml:"filter"`
+	Alert          Alert         `json:"alert" yaml:"alert"`
+	History        History       `json:"history" yaml:"history"`
+	Notify         Notify        `json:"notify" yaml:"notify"`
+	Server         Server        `json:"server" yaml:"server"`
+	UI             UI            `json:"ui" yaml:"ui"`
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o Config) MarshalJSON() ([]byte, error) {
+	type alias Config
+	return json.Marshal(struct {
+		alias
+		Period   string `json:"period"`
+		Lateness string `json:"lateness"`
+	}{alias(o), o.Period.String(), o.Lateness.String()})
 }
 
 // Default creates a new default configuration structure
 func Default() Config {
 	return Config{
 		Period:         10 * time.Second,
+		Retention:      360,
 		ReadBufferSize: 100,
+		Parser:         Parser{}.Default(),
+		Filter:         Filter{}.Default(),
 		Alert:          Alert{}.Default(),
+		History:        History{}.Default(),
+		Notify:         Notify{}.Default(),
+		Server:         Server{}.Default(),
+		UI:             UI{}.Default(),
 	}
 }
 
-// CLI parses the cli, overriding matching existing config fields
+// envPrefix prefixes environment variables overriding flags,
+// HTTPMON_ALERT_THRESHOLD overrides --alert-threshold for instance
+const envPrefix string = "HTTPMON_"
+
+// CLI parses the cli, overriding matching existing config fields.
+//
+// Values are read by increasing precedence from conf, the --config file,
+// HTTPMON_* environment variables then flags.
 func CLI(conf Config) (Config, error) {
+	return parse(flag.CommandLine, os.Args[1:], os.LookupEnv, conf)
+}
+
+func parse(fs *flag.FlagSet, args []string, lookupEnv func(string) (string, bool), conf Config) (Config, error) {
+	// The file sets the flags' default values so it is loaded first
+	path := configPath(args, lookupEnv)
+	if path != "" {
+		var err error
+		if conf, err = File(path, conf); err != nil {
+			return conf, err
+		}
+	}
+
 	cli := cliInput{}
-	flag.BoolVar(&cli.debug, "debug", false, "wait a few seconds before starting")
-	flag.StringVar(&cli.file, "file", conf.File, "csv file to read http traces from")
-	flag.BoolVar(&cli.stdin, "stdin", false, "read http logs from stdin, takes precendence over --file")
-	flag.DurationVar(&cli.period, "period", conf.Period, "log aggregation period used to generate metrics values (go duration format)")
-	flag.UintVar(&cli.bufferLen, "lines", conf.ReadBufferSize, "size of the line buffer when reading logs")
-	flag.DurationVar(&cli.alertDuration, "alert-duration", conf.Alert.RequestsPerSecond.Period, "if requests/s > --threshold for --alert-duration then the alert is active (go duration format)")
-	flag.UintVar(&cli.alertThreshold, "alert-threshold", uint(conf.Alert.RequestsPerSecond.Threshold), "requests/s threshold over wich the alert becomes active")
-	flag.Parse()
+	fs.StringVar(&cli.config, "config", path, "yaml or json configuration file, flags and HTTPMON_* environment variables take precedence over it")
+	fs.BoolVar(&cli.debug, "debug", conf.Debug, "wait a few seconds before starting")
+	fs.StringVar(&cli.file, "file", conf.File, "csv file to read http traces from")
+	fs.BoolVar(&cli.stdin, "stdin", conf.File == "stdin", "read http logs from stdin, takes precendence over --file")
+	fs.StringVar(&cli.parser, "parser", conf.Parser.Name, fmt.Sprintf("log format of the input stream: %s or %s to detect it", strings.Join(parser.Names(), ", "), AutoParser))
+	fs.UintVar(&cli.detectLines, "detect-lines", conf.Parser.DetectLines, "number of lines sampled to detect the log format with --parser auto")
+	fs.StringVar(&cli.format, "format", conf.Parser.Format, "log format template of the nginx and apache parsers, an nginx log_format or Apache LogFormat string (default combined)")
+	fs.StringVar(&cli.csvDelimiter, "csv-delimiter", conf.Parser.CSVDelimiter, "field delimiter of the csv parser, a single character or tab")
+	fs.StringVar(&cli.csvHeader, "csv-header", conf.Parser.CSVHeader, "comma separated csv column names, for files without header line (required: date, request, status)")
+	fs.StringVar(&cli.jsonlKeys, "jsonl-keys", "", "comma separated field=key list overriding the json keys read by the jsonl parser (fields: host, user, timestamp, method, path, protocol, status, bytes, latency)")
+	fs.StringVar(&cli.sections, "sections", strings.Join(conf.Filter.Sections, ","), "comma separated list of sections metrics are collected for, all if empty")
+	fs.StringVar(&cli.excludeHosts, "exclude-hosts", strings.Join(conf.Filter.ExcludeHosts, ","), "comma separated list of remote hosts whose requests are ignored")
+	fs.DurationVar(&cli.period, "period", conf.Period, "log aggregation period used to generate metrics values (go duration format)")
+	fs.StringVar(&cli.retention, "retention", fmt.Sprint(conf.Retention),
+		"history kept per time series, as a number of --period data points (e.g. 360) or a go duration (e.g. 1h). "+
+			"Memory grows with retention times the number of sections: globally and per section, "+
+			"series are kept for the request rate, every latency quantile, every status class and response sizes")
+	fs.DurationVar(&cli.lateness, "lateness", conf.Lateness, "maximum delay, in log time, of out of order traces. Traces are held that long to be sorted by date, later ones are dropped. 0 disables reordering (go duration format)")
+	fs.UintVar(&cli.bufferLen, "lines", conf.ReadBufferSize, "size of the line buffer when reading logs")
+	fs.DurationVar(&cli.alertDuration, "alert-duration", conf.Alert.RequestsPerSecond.Period, "if requests/s > --threshold for --alert-duration then the alert is active (go duration format)")
+	fs.Float64Var(&cli.alertThreshold, "alert-threshold", conf.Alert.RequestsPerSecond.Threshold, "requests/s threshold over wich the alert becomes active")
+	fs.StringVar(&cli.historyFile, "history-file", conf.History.File, "JSON lines journal of alert state changes, loaded on startup. History is only kept in memory if empty")
+	fs.DurationVar(&cli.historyRetention, "history-retention", conf.History.Retention, "how long alert state changes are kept in the history, in log time (go duration format)")
+	fs.StringVar(&cli.listen, "listen", conf.Server.Listen, "address to serve prometheus metrics on /metrics from, host:port or :port. Disabled if empty")
+	fs.BoolVar(&cli.debugEndpoints, "debug-endpoints", conf.Server.Debug, "expose /debug/pprof profiles and /debug/state internals, requires --listen. Profiling has a cost, only enable it to diagnose an instance")
+	fs.BoolVar(&cli.adminEndpoints, "admin-endpoints", conf.Server.Admin, "expose POST /-/reload and the creation and expiry of silences on /api/silences, requires --listen. They are not authenticated, only enable them on a trusted network")
+	fs.BoolVar(&cli.headless, "headless", conf.UI.Headless, "run without the terminal UI, requires --listen")
+	fs.DurationVar(&cli.refresh, "refresh", conf.UI.Refresh, "refresh interval of the terminal UI (go duration format)")
+
+	if err := fromEnv(fs, lookupEnv); err != nil {
+		return conf, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return conf, err
+	}
 
 	if err := fromCLI(&conf, cli); err != nil {
 		return conf, err
 	}
 
+	// The file is checked when loaded so that errors have line numbers,
+	// the whole configuration is checked once overridden
+	if err := conf.validate(); err != nil {
+		return conf, err
+	}
+
 	return conf, nil
 }
 
+// configPath looks up --config in args, then in the environment
+func configPath(args []string, lookupEnv func(string) (string, bool)) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	path, _ := lookupEnv(envName("config"))
+	return path
+}
+
+// fromEnv sets flags from their HTTPMON_* environment variable, if defined
+func fromEnv(fs *flag.FlagSet, lookupEnv func(string) (string, bool)) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := envName(f.Name)
+		value, ok := lookupEnv(name)
+		if !ok || err != nil {
+			return
+		}
+		if e := fs.Set(f.Name, value); e != nil {
+			err = fmt.Errorf("%s - %w", name, e)
+		}
+	})
+
+	return err
+}
+
+// envName returns the name of the environment variable overriding a flag
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
 type cliInput struct {
-	debug          bool
-	file           string
-	stdin          bool
-	period         time.Duration
-	bufferLen      uint
-	alertDuration  time.Duration
-	alertThreshold uint
+	config           string
+	debug            bool
+	file             string
+	stdin            bool
+	parser           string
+	format           string
+	detectLines      uint
+	csvDelimiter     string
+	csvHeader        string
+	jsonlKeys        string
+	sections         string
+	excludeHosts     string
+	period           time.Duration
+	retention        string
+	lateness         time.Duration
+	bufferLen        uint
+	alertDuration    time.Duration
+	alertThreshold   float64
+	historyFile      string
+	historyRetention time.Duration
+	listen           string
+	headless         bool
+	debugEndpoints   bool
+	adminEndpoints   bool
+	refresh          time.Duration
 }
 
 func cliValidation(cli cliInput) error {
@@ -59,16 +206,52 @@ func cliValidation(cli cliInput) error {
 		}
 	}
 
-	if cli.period < time.Second {
-		return fmt.Errorf("--period - minimum period is 1s, received %s", cli.period)
+	if _, err := jsonlKeys(cli.jsonlKeys); err != nil {
+		return fmt.Errorf("--jsonl-keys - %w", err)
+	}
+
+	if err := checkPeriod(cli.period); err != nil {
+		return fmt.Errorf("--period - %w", err)
+	}
+
+	if _, err := retentionPoints(cli.retention, cli.period); err != nil {
+		return fmt.Errorf("--retention - %w", err)
+	}
+
+	if err := checkLateness(cli.lateness); err != nil {
+		return fmt.Errorf("--lateness - %w", err)
+	}
+
+	if err := checkBufferLen(cli.bufferLen); err != nil {
+		return fmt.Errorf("--lines - %w", err)
+	}
+
+	if err := checkPeriod(cli.alertDuration); err != nil {
+		return fmt.Errorf("--alert-duration - %w", err)
+	}
+
+	if err := checkThreshold(cli.alertThreshold); err != nil {
+		return fmt.Errorf("--alert-threshold - %w", err)
+	}
+
+	if err := checkTimeout(cli.historyRetention); err != nil {
+		return fmt.Errorf("--history-retention - %w", err)
 	}
 
-	if cli.bufferLen == 0 {
-		return fmt.Errorf("--lines - buffer length must be greater than 0")
+	if cli.headless && cli.listen == "" {
+		return fmt.Errorf("--headless - requires --listen, metrics would not be exposed")
 	}
 
-	if cli.alertDuration < time.Second {
-		return fmt.Errorf("--alert-duration - minimum period is 1s, received %s", cli.period)
+	if cli.debugEndpoints && cli.listen == "" {
+		return fmt.Errorf("--debug-endpoints - requires --listen")
+	}
+
+	if cli.adminEndpoints && cli.listen == "" {
+		return fmt.Errorf("--admin-endpoints - requires --listen")
+	}
+
+	if err := checkRefresh(cli.refresh); err != nil {
+		return fmt.Errorf("--refresh - %w", err)
 	}
 
 	return nil
@@ -90,10 +273,162 @@ func fromCLI(conf *Config, cli cliInput) error {
 		conf.File = cli.file
 	}
 
+	conf.Parser.Name = strings.ToLower(cli.parser)
+	conf.Parser.Format = cli.format
+	conf.Parser.DetectLines = cli.detectLines
+	conf.Parser.CSVDelimiter = cli.csvDelimiter
+	conf.Parser.CSVHeader = cli.csvHeader
+	keys, _ := jsonlKeys(cli.jsonlKeys) // validated above
+	for field, key := range keys {
+		conf.Parser.JSONLKeys[field] = key
+	}
+	if err := conf.Parser.validate(); err != nil {
+		return fmt.Errorf("--parser - %w", err)
+	}
+
+	conf.Filter.Sections = list(cli.sections)
+	conf.Filter.ExcludeHosts = list(cli.excludeHosts)
 	conf.Period = cli.period
+	conf.Retention, _ = retentionPoints(cli.retention, cli.period) // validated above
+	conf.Lateness = cli.lateness
 	conf.ReadBufferSize = cli.bufferLen
 	conf.Alert.RequestsPerSecond.Period = cli.alertDuration
-	conf.Alert.RequestsPerSecond.Threshold = float64(cli.alertThreshold)
+	conf.Alert.RequestsPerSecond.Threshold = cli.alertThreshold
+	conf.History.File = cli.historyFile
+	conf.History.Retention = cli.historyRetention
+	conf.Server.Listen = cli.listen
+	conf.Server.Debug = cli.debugEndpoints
+	conf.Server.Admin = cli.adminEndpoints
+	conf.UI.Headless = cli.headless
+	conf.UI.Refresh = cli.refresh
+
+	return nil
+}
+
+// validate checks values which do not depend on each other,
+// errors are fieldErrors
+func (o Config) validate() error {
+	checks := []fieldError{
+		{"period", checkPeriod(o.Period)},
+		{"retention", checkRetention(o.Retention)},
+		{"lateness", checkLateness(o.Lateness)},
+		{"read_buffer_size", checkBufferLen(o.ReadBufferSize)},
+		{"parser", o.Parser.validate()},
+		{"alert.period", checkPeriod(o.Alert.Period)},
+		{"alert.requests_per_second.period", checkPeriod(o.Alert.RequestsPerSecond.Period)},
+		{"alert.requests_per_second.threshold", checkThreshold(o.Alert.RequestsPerSecond.Threshold)},
+		{"history.retention", checkTimeout(o.History.Retention)},
+		{"ui.refresh", checkRefresh(o.UI.Refresh)},
+	}
+	checks = append(checks, o.Alert.RequestsPerSecond.Resolve.checks("alert.requests_per_second.resolve", checkThreshold)...)
+	checks = append(checks, o.Notify.checks()...)
+
+	for _, c := range checks {
+		if c.err != nil {
+			return c
+		}
+	}
+	return o.Alert.validate()
+}
+
+// fieldError is an invalid configuration field, key is its
+// path in configuration files such as "alert.period"
+type fieldError struct {
+	key string
+	err error
+}
+
+func (o fieldError) Error() string {
+	return fmt.Sprintf("%s - %s", o.key, o.err)
+}
+
+func (o fieldError) Unwrap() error {
+	return o.err
+}
+
+func checkPeriod(d time.Duration) error {
+	if d < time.Second {
+		return fmt.Errorf("minimum period is 1s, received %s", d)
+	}
+	return nil
+}
+
+func checkRetention(points uint) error {
+	if points == 0 {
+		return fmt.Errorf("at least one point must be retained")
+	}
+	return nil
+}
+
+func checkLateness(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("must be positive, received %s", d)
+	}
+	return nil
+}
 
+func checkBufferLen(n uint) error {
+	if n == 0 {
+		return fmt.Errorf("buffer length must be greater than 0")
+	}
+	return nil
+}
+
+func checkThreshold(t float64) error {
+	if t < 0 {
+		return fmt.Errorf("must be positive, received %g", t)
+	}
 	return nil
 }
+
+func checkRefresh(d time.Duration) error {
+	if d < 10*time.Millisecond {
+		return fmt.Errorf("minimum refresh interval is 10ms, received %s", d)
+	}
+	return nil
+}
+
+// jsonlKeys parses a "field=key,field=key" list into a map
+func jsonlKeys(list string) (map[string]string, error) {
+	keys := make(map[string]string)
+	if list == "" {
+		return keys, nil
+	}
+
+	check := parser.DefaultJSONLKeys()
+	for _, pair := range strings.Split(list, ",") {
+		field, key, found := strings.Cut(pair, "=")
+		field, key = strings.TrimSpace(field), strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("expected field=key, received %q", pair)
+		}
+		if err := check.Override(field, key); err != nil {
+			return nil, err
+		}
+		keys[strings.ToLower(field)] = key
+	}
+
+	return keys, nil
+}
+
+// retentionPoints converts a retention, either a number of points or a
+// duration, into a number of points of period
+func retentionPoints(retention string, period time.Duration) (uint, error) {
+	points, err := strconv.ParseUint(retention, 10, 32)
+	if err != nil {
+		d, err := time.ParseDuration(retention)
+		if err != nil {
+			return 0, fmt.Errorf("expected a number of points or a duration, received %q", retention)
+		}
+		if period <= 0 {
+			return 0, fmt.Errorf("invalid period %s", period)
+		}
+		// Round up so that at least the duration is kept
+		points = uint64((d + period - 1) / period)
+	}
+
+	if err := checkRetention(uint(points)); err != nil {
+		return 0, err
+	}
+	return uint(points), nil
+}
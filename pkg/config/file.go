@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File loads a yaml or json configuration file over conf.
+// Keys missing from the file keep their value from conf, unknown keys
+// are errors. Errors are prefixed by the file name and line.
+//
+// Keys are the json names of /config, for instance:
+//
+//	period: 10s
+//	parser:
+//	  name: auto
+//	alert:
+//	  requests_per_second:
+//	    threshold: 20
+func File(path string, conf Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return conf, fmt.Errorf("--config - %w", err)
+	}
+
+	return load(path, data, conf)
+}
+
+// load decodes data, named name in errors, over conf
+func load(name string, data []byte, conf Config) (Config, error) {
+	// JSON being YAML, both are read the same way
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return conf, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(doc.Content) == 0 {
+		// Empty file
+		return conf, nil
+	}
+	root := doc.Content[0]
+
+	if err := checkKeys(root, reflect.TypeOf(conf), ""); err != nil {
+		return conf, fmt.Errorf("%s:%w", name, err)
+	}
+
+	// Retention is a number of points or a duration converted with the
+	// period, which may be in the file too: it is converted once decoded
+	_, retention := lookupNode(root, "retention")
+	var rawRetention string
+	if retention != nil {
+		rawRetention = retention.Value
+		retention.Tag, retention.Value = "!!int", "1"
+	}
+
+	n := conf
+	n.Parser.JSONLKeys = maps.Clone(conf.Parser.JSONLKeys) // not to modify conf
+	if err := root.Decode(&n); err != nil {
+		return conf, fmt.Errorf("%s: %w", name, err)
+	}
+	n.Parser.Name = strings.ToLower(n.Parser.Name)
+
+	if retention != nil {
+		points, err := retentionPoints(rawRetention, n.Period)
+		if err != nil {
+			return conf, fmt.Errorf("%s:%d: retention - %w", name, retention.Line, err)
+		}
+		n.Retention = points
+	}
+
+	if err := n.validate(); err != nil {
+		var field fieldError
+		if errors.As(err, &field) {
+			// Missing keys are reported at their closest parent
+			for path := field.key; path != ""; path, _ = cutLast(path) {
+				if key, _ := lookupNode(root, path); key != nil {
+					return conf, fmt.Errorf("%s:%d: %w", name, key.Line, err)
+				}
+			}
+		}
+		return conf, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return n, nil
+}
+
+// checkKeys returns an error if a key of node is not a yaml field of t,
+// path is the key of node
+func checkKeys(node *yaml.Node, t reflect.Type, path string) error {
+	if node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice {
+		for i, item := range node.Content {
+			if err := checkKeys(item, t.Elem(), fmt.Sprintf("%s.%d", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if node.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+		// Values are checked when decoded
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		name := key.Value
+		if path != "" {
+			name = path + "." + key.Value
+		}
+
+		field, ok := yamlField(t, key.Value)
+		if !ok {
+			return fmt.Errorf("%d: unknown key %s", key.Line, name)
+		}
+		if err := checkKeys(value, field.Type, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// yamlField returns the field of t whose yaml name is name
+func yamlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// lookupNode returns the key and value nodes at a dotted path such as
+// "alert.period", nil if it is not found. Sequence items are indexed
+// by their position, such as "alert.rules.0", their key is the item.
+func lookupNode(node *yaml.Node, path string) (*yaml.Node, *yaml.Node) {
+	key, rest, nested := strings.Cut(path, ".")
+	if node.Kind == yaml.SequenceNode {
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(node.Content) {
+			return nil, nil
+		}
+		if !nested {
+			return node.Content[i], node.Content[i]
+		}
+		return lookupNode(node.Content[i], rest)
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+		if !nested {
+			return node.Content[i], node.Content[i+1]
+		}
+		return lookupNode(node.Content[i+1], rest)
+	}
+	return nil, nil
+}
+
+// cutLast splits a dotted path before its last key
+func cutLast(path string) (string, string) {
+	i := strings.LastIndex(path, ".")
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
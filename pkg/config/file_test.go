@@ -0,0 +1,136 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadYAML(t *testing.T) {
+	data := `
+file: access.log
+period: 5s
+retention: 1m
+parser:
+  name: JSONL
+  jsonl_keys:
+    status: http.status
+alert:
+  requests_per_second:
+    threshold: 2.5
+    resolve:
+      threshold: 1.5
+      keep_firing_for: 30s
+`
+	conf, err := load("httpmon.yaml", []byte(data), Default())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access.log", conf.File)
+	assert.Equal(t, 5*time.Second, conf.Period)
+	assert.Equal(t, uint(12), conf.Retention)
+	assert.Equal(t, "jsonl", conf.Parser.Name)
+	assert.Equal(t, map[string]string{"status": "http.status"}, conf.Parser.JSONLKeys)
+	assert.Equal(t, 2.5, conf.Alert.RequestsPerSecond.Threshold)
+	assert.Equal(t, alert.ResolveInput{Recovery: 1.5, HasRecovery: true, KeepFiringFor: 30 * time.Second},
+		conf.Alert.RequestsPerSecond.Resolve.Input())
+	// Missing keys keep their value
+	assert.Equal(t, time.Minute, conf.Alert.RequestsPerSecond.Period)
+	assert.Equal(t, uint(100), conf.ReadBufferSize)
+	assert.Empty(t, Default().Parser.JSONLKeys)
+}
+
+func TestLoadJSON(t *testing.T) {
+	data := "{\n\t\"period\": \"20s\",\n\t\"ui\": {\"refresh\": \"1s\"}\n}\n"
+	conf, err := load("httpmon.json", []byte(data), Default())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 20*time.Second, conf.Period)
+	assert.Equal(t, time.Second, conf.UI.Refresh)
+}
+
+func TestLoadRules(t *testing.T) {
+	data := `
+alert:
+  rules:
+    - name: API errors
+      metric: RoutesPerStatus
+      selector:
+        section: /api
+        status: "500"
+      operator: ">="
+      threshold: 10
+      for: 30s
+`
+	conf, err := load("httpmon.yaml", []byte(data), Default())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{{
+		Name:      "API errors",
+		Metric:    "RoutesPerStatus",
+		Selector:  map[string]string{"section": "/api", "status": "500"},
+		Operator:  ">=",
+		Threshold: 10,
+		For:       30 * time.Second,
+	}}, conf.Alert.Rules)
+	assert.Equal(t, alert.Last, conf.Alert.Rules[0].Input().Aggregation)
+}
+
+func TestLoadSilences(t *testing.T) {
+	data := `
+alert:
+  silences:
+    - matchers:
+        alertname: Requests Per Second Threshold
+      starts_at: 2026-10-18T10:00:00Z
+      ends_at: 2026-10-18T11:00:00Z
+      comment: load test
+  inhibitions:
+    - source: {alertname: Requests Per Second Threshold}
+      target: {section: .+}
+`
+	conf, err := load("httpmon.yaml", []byte(data), Default())
+
+	assert.NoError(t, err)
+	s := conf.Alert.Silences[0].Input("config-0")
+	assert.Equal(t, time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC), s.StartsAt)
+	assert.Equal(t, time.Hour, s.EndsAt.Sub(s.StartsAt))
+	assert.True(t, s.Matchers.Match(alert.Labels{alert.AlertName: string(alert.ReqPerS)}))
+	assert.Equal(t, map[string]string{"section": ".+"}, conf.Alert.Inhibitions[0].Target)
+}
+
+func TestLoadErrorsHaveLineNumbers(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"period: 5s\nalert:\n  threshold: 3\n", "httpmon.yaml:3: unknown key alert.threshold"},
+		{"period: 5s\nlines: 3\n", "httpmon.yaml:2: unknown key lines"},
+		{"file: a.log\nperiod: 500ms\n", "httpmon.yaml:2: period - minimum period is 1s, received 500ms"},
+		{"alert:\n  period: 100ms\n", "httpmon.yaml:2: alert.period - minimum period is 1s, received 100ms"},
+		{"alert:\n  requests_per_second:\n    period: 0s\n", "httpmon.yaml:3: alert.requests_per_second.period - minimum period is 1s, received 0s"},
+		{"alert:\n  requests_per_second:\n    resolve:\n      threshold: -1\n", "httpmon.yaml:4: alert.requests_per_second.resolve.threshold - must be positive, received -1"},
+		{"retention: forever\n", `httpmon.yaml:1: retention - expected a number of points or a duration, received "forever"`},
+		{"alert:\n  rules:\n    - name: errors\n      metric: RoutesPerStatus\n      operator: =>\n", `httpmon.yaml:5: alert.rules.0.operator - unsupported operator "=>", expecting >, >=, <, <=, == or !=`},
+		{"alert:\n  rules:\n    - name: slow\n      metric: Latency\n      operator: \">\"\n      aggregation: avg\n", "httpmon.yaml:6: alert.rules.0.aggregation - avg needs at least 1 point, received 0"},
+		{"alert:\n  rules:\n    - name: slow\n      metric: Latency\n", `httpmon.yaml:3: alert.rules.0.operator - unsupported operator "", expecting >, >=, <, <=, == or !=`},
+		{"alert:\n  rules:\n    - name: busy\n      metric: ReqsPerHost\n      by: section\n", `httpmon.yaml:5: alert.rules.0.by - ReqsPerHost has no label "section", expecting one of host`},
+		{"alert:\n  error_ratios:\n    - name: errors\n      classes: [5xx]\n      window: 2m\n      threshold: 5\n", "httpmon.yaml:6: alert.error_ratios.0.threshold - must be in [0, 1], received 5"},
+		{"alert:\n  error_ratios:\n    - name: errors\n      classes: [500]\n", `httpmon.yaml:4: alert.error_ratios.0.classes - status classes are 1xx to 5xx, received "500"`},
+		{"alert:\n  rules:\n    - name: slow\n      labels: {}\n", "httpmon.yaml:4: unknown key alert.rules.0.labels"},
+		{"notify:\n  smtp:\n    - addr: localhost\n      from: httpmon@example.com\n      to: [oncall@example.com]\n", `httpmon.yaml:3: notify.smtp.0.addr - expecting host:port, received "localhost"`},
+		{"notify:\n  exec:\n    - command: []\n", "httpmon.yaml:3: notify.exec.0.command - a command is needed"},
+		{"alert:\n  absent:\n    - name: dead\n      section: /api\n      host: 10.0.0.1\n      after: 5m\n", `httpmon.yaml:5: alert.absent.0.host - expecting either a section or a host, received "/api" and "10.0.0.1"`},
+		{"alert:\n  absent:\n    - name: dead\n", "httpmon.yaml:3: alert.absent.0.after - minimum period is 1s, received 0s"},
+		{"alert:\n  silences:\n    - matchers: {alertname: busy}\n      starts_at: 2026-10-18T10:00:00Z\n      ends_at: 2026-10-18T09:00:00Z\n", "httpmon.yaml:5: alert.silences.0.ends_at - must be after starts_at, received 2026-10-18T09:00:00Z"},
+		{"alert:\n  inhibitions:\n    - source: {alertname: busy}\n      target: {}\n", "httpmon.yaml:4: alert.inhibitions.0.target - at least a matcher is needed"},
+		{"history:\n  retention: 0s\n", "httpmon.yaml:2: history.retention - must be greater than 0, received 0s"},
+		{"period: ten\n", "httpmon.yaml: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `ten` into time.Duration"},
+	}
+
+	for _, test := range tests {
+		_, err := load("httpmon.yaml", []byte(test.data), Default())
+		assert.EqualError(t, err, test.err)
+	}
+}
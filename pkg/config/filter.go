@@ -0,0 +1,30 @@
+package config
+
+import "strings"
+
+// Filter selects the traces metrics are collected from,
+// to reduce cardinality
+type Filter struct {
+	// Sections only keeps traces of these sections, all if empty
+	Sections []string `json:"sections" yaml:"sections"`
+	// ExcludeHosts drops traces of these remote hosts
+	ExcludeHosts []string `json:"exclude_hosts" yaml:"exclude_hosts"`
+}
+
+func (o Filter) Default() Filter {
+	return Filter{
+		Sections:     []string{},
+		ExcludeHosts: []string{},
+	}
+}
+
+// list splits a comma separated list, ignoring empty values
+func list(s string) []string {
+	values := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// History configures the history of alert state changes
+type History struct {
+	// File is a JSON lines journal of alert state changes, loaded
+	// on startup. History is only kept in memory if empty.
+	File string `json:"file" yaml:"file"`
+	// Retention is how long state changes are kept, in log time
+	Retention time.Duration `json:"retention" yaml:"retention"`
+}
+
+func (o History) Default() History {
+	return History{
+		Retention: 7 * 24 * time.Hour,
+	}
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o History) MarshalJSON() ([]byte, error) {
+	type alias History
+	return json.Marshal(struct {
+		alias
+		Retention string `json:"retention"`
+	}{alias(o), o.Retention.String()})
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Notify configures where alert notifications are sent, on firing
+// and resolved transitions
+type Notify struct {
+	// QueueSize is the number of notifications waiting per receiver,
+	// notifications are dropped when it is full
+	QueueSize uint `json:"queue_size" yaml:"queue_size"`
+	// Timeout of a delivery attempt
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// Retries is the number of attempts after a failed delivery
+	Retries uint `json:"retries" yaml:"retries"`
+	// Backoff is the wait before the first retry, doubled on every retry
+	Backoff time.Duration `json:"backoff" yaml:"backoff"`
+	// Webhooks are POSTed notifications in the Alertmanager webhook format
+	Webhooks []Webhook `json:"webhooks" yaml:"webhooks"`
+	// Exec are commands run on every notification
+	Exec []Exec `json:"exec" yaml:"exec"`
+	// SMTP are email receivers
+	SMTP []SMTP `json:"smtp" yaml:"smtp"`
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o Notify) MarshalJSON() ([]byte, error) {
+	type alias Notify
+	return json.Marshal(struct {
+		alias
+		Timeout string `json:"timeout"`
+		Backoff string `json:"backoff"`
+	}{alias(o), o.Timeout.String(), o.Backoff.String()})
+}
+
+func (o Notify) Default() Notify {
+	return Notify{
+		QueueSize: 100,
+		Timeout:   10 * time.Second,
+		Retries:   3,
+		Backoff:   time.Second,
+		Webhooks:  []Webhook{},
+		Exec:      []Exec{},
+		SMTP:      []SMTP{},
+	}
+}
+
+// secret replaces sensitive values in the json configuration
+const secret = "<secret>"
+
+// Webhook is an http receiver of notifications
+type Webhook struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+// MarshalJSON hides everything but the url's scheme and host,
+// paths and queries often embed tokens
+func (o Webhook) MarshalJSON() ([]byte, error) {
+	type alias Webhook
+	return json.Marshal(struct {
+		alias
+		URL string `json:"url"`
+	}{alias(o), RedactURL(o.URL)})
+}
+
+// RedactURL returns s's scheme and host, the rest is replaced by <secret>
+func RedactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		if s == "" {
+			return ""
+		}
+		return secret
+	}
+	r := u.Scheme + "://" + u.Host
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		r += "/" + secret
+	}
+	return r
+}
+
+// checks returns the checks of o, errors are fieldErrors
+func (o Notify) checks() []fieldError {
+	checks := []fieldError{
+		{"notify.queue_size", checkBufferLen(o.QueueSize)},
+		{"notify.timeout", checkTimeout(o.Timeout)},
+		{"notify.backoff", checkLateness(o.Backoff)},
+	}
+	for i, w := range o.Webhooks {
+		checks = append(checks, fieldError{fmt.Sprintf("notify.webhooks.%d.url", i), checkURL(w.URL)})
+	}
+	for i, e := range o.Exec {
+		checks = append(checks, fieldError{fmt.Sprintf("notify.exec.%d.command", i), checkCommand(e.Command)})
+	}
+	for i, s := range o.SMTP {
+		key := fmt.Sprintf("notify.smtp.%d", i)
+		checks = append(checks,
+			fieldError{key + ".addr", checkAddr(s.Addr)},
+			fieldError{key + ".from", checkNotEmpty(s.From)},
+			fieldError{key + ".to", checkNotEmpty(strings.Join(s.To, ""))},
+			fieldError{key + ".subject", checkTemplate(s.Subject)},
+			fieldError{key + ".body", checkTemplate(s.Body)},
+		)
+	}
+	return checks
+}
+
+func checkCommand(command []string) error {
+	if len(command) == 0 || command[0] == "" {
+		return fmt.Errorf("a command is needed")
+	}
+	return nil
+}
+
+func checkAddr(addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("expecting host:port, received %q", addr)
+	}
+	return nil
+}
+
+func checkNotEmpty(s string) error {
+	if s == "" {
+		return fmt.Errorf("can't be empty")
+	}
+	return nil
+}
+
+func checkTemplate(s string) error {
+	_, err := template.New("").Parse(s)
+	return err
+}
+
+func checkTimeout(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("must be greater than 0, received %s", d)
+	}
+	return nil
+}
+
+func checkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("expecting an http or https url, received %q", s)
+	}
+	return nil
+}
+
+// Exec is a command receiving notifications as json on its stdin,
+// and in HTTPMON_ALERT_* environment variables
+type Exec struct {
+	// Command is the program then its arguments, it is not run in a shell
+	Command []string `json:"command" yaml:"command"`
+}
+
+// MarshalJSON hides the command's arguments, only the program is shown
+func (o Exec) MarshalJSON() ([]byte, error) {
+	type alias Exec
+	command := make([]string, len(o.Command))
+	for i, arg := range o.Command {
+		command[i] = secret
+		if i == 0 {
+			command[i] = arg
+		}
+	}
+	return json.Marshal(struct {
+		alias
+		Command []string `json:"command"`
+	}{alias(o), command})
+}
+
+// SMTP is an email receiver of notifications
+type SMTP struct {
+	// Addr is the server's host:port, STARTTLS is used if supported
+	Addr string   `json:"addr" yaml:"addr"`
+	From string   `json:"from" yaml:"from"`
+	To   []string `json:"to" yaml:"to"`
+	// Username and Password authenticate with PLAIN auth if Username is set
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	// Subject and Body are go text templates executed over the
+	// Alertmanager webhook message, defaults are used if empty
+	Subject string `json:"subject" yaml:"subject"`
+	Body    string `json:"body" yaml:"body"`
+}
+
+// MarshalJSON hides the password
+func (o SMTP) MarshalJSON() ([]byte, error) {
+	type alias SMTP
+	password := ""
+	if o.Password != "" {
+		password = secret
+	}
+	return json.Marshal(struct {
+		alias
+		Password string `json:"password"`
+	}{alias(o), password})
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/julnicolas/httpmon/pkg/parser"
+)
+
+// AutoParser is the parser name selecting the format detection mode
+const AutoParser string = "auto"
+
+// Parser configures how raw logs are turned into traces
+type Parser struct {
+	// Name of a registered parser (see parser.Names) or "auto"
+	// to detect the format from the first DetectLines lines.
+	Name string `json:"name" yaml:"name"`
+	// Format is the log format template of the nginx and apache parsers
+	Format string `json:"format" yaml:"format"`
+	// JSONLKeys overrides the JSON keys read by the jsonl parser,
+	// keys are trace field names (host, user, timestamp, method,
+	// path, protocol, status, bytes, latency)
+	JSONLKeys map[string]string `json:"jsonl_keys" yaml:"jsonl_keys"`
+	// DetectLines is the number of lines sampled to detect the format
+	DetectLines uint `json:"detect_lines" yaml:"detect_lines"`
+	// CSVDelimiter is the csv field delimiter, a single character
+	// or "tab"
+	CSVDelimiter string `json:"csv_delimiter" yaml:"csv_delimiter"`
+	// CSVHeader is a comma separated list of csv column names,
+	// for streams without header
+	CSVHeader string `json:"csv_header" yaml:"csv_header"`
+}
+
+func (o Parser) Default() Parser {
+	return Parser{
+		Name:         "csv",
+		JSONLKeys:    make(map[string]string),
+		DetectLines:  10,
+		CSVDelimiter: ",",
+	}
+}
+
+// Options returns the options to create parsers with
+func (o Parser) Options() (parser.Options, error) {
+	opts := parser.DefaultOptions()
+	opts.Format = o.Format
+
+	delimiter, err := o.delimiter()
+	if err != nil {
+		return opts, err
+	}
+	opts.CSVDelimiter = delimiter
+
+	if o.CSVHeader != "" {
+		opts.CSVHeader = strings.Split(o.CSVHeader, ",")
+	}
+
+	for field, key := range o.JSONLKeys {
+		if err := opts.JSONLKeys.Override(field, key); err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+// delimiter converts CSVDelimiter to a rune
+func (o Parser) delimiter() (rune, error) {
+	switch o.CSVDelimiter {
+	case "tab", `\t`:
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(o.CSVDelimiter)
+	if size == 0 || size != len(o.CSVDelimiter) {
+		return 0, fmt.Errorf("csv delimiter must be a single character, received %q", o.CSVDelimiter)
+	}
+	return r, nil
+}
+
+// validate makes sure the configured parser can be created
+func (o Parser) validate() error {
+	opts, err := o.Options()
+	if err != nil {
+		return err
+	}
+
+	if o.Name != AutoParser {
+		_, err := parser.New(o.Name, opts)
+		return err
+	}
+
+	if o.DetectLines == 0 {
+		return fmt.Errorf("at least one line must be sampled to detect the log format")
+	}
+
+	// At least one parser must be able to compete
+	for _, name := range parser.Names() {
+		if _, err := parser.New(name, opts); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no parser can be created from the configuration")
+}
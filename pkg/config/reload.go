@@ -0,0 +1,85 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Changes lists the configuration keys which changed on reload
+type Changes struct {
+	// Applied keys are swapped at runtime
+	Applied []string `json:"applied"`
+	// Restart keys keep their previous value until the app is restarted
+	Restart []string `json:"restart_required"`
+}
+
+// Reload reads the configuration again from defaults, the --config file,
+// environment variables then flags, like CLI did.
+//
+// It returns old updated with the values which can change at runtime,
+// alerts, filters and ui.refresh, along with the changed keys.
+// On error old is returned unchanged.
+func Reload(old Config) (Config, Changes, error) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	conf, err := parse(fs, os.Args[1:], os.LookupEnv, Default())
+	if err != nil {
+		return old, Changes{}, err
+	}
+
+	conf, changes := reload(old, conf)
+	return conf, changes, nil
+}
+
+// reload returns old updated with the reloadable values of conf
+func reload(old, conf Config) (Config, Changes) {
+	changes := Changes{Applied: []string{}, Restart: []string{}}
+	cur := old
+
+	// Values which can change at runtime
+	reloadable := []struct {
+		key   string
+		old   interface{}
+		new   interface{}
+		apply func()
+	}{
+		{"filter", old.Filter, conf.Filter, func() { cur.Filter = conf.Filter }},
+		{"alert", old.Alert, conf.Alert, func() { cur.Alert = conf.Alert }},
+		{"ui.refresh", old.UI.Refresh, conf.UI.Refresh, func() { cur.UI.Refresh = conf.UI.Refresh }},
+	}
+	for _, r := range reloadable {
+		if !reflect.DeepEqual(r.old, r.new) {
+			r.apply()
+			changes.Applied = append(changes.Applied, r.key)
+		}
+	}
+
+	// Values the app is built from
+	restart := []struct {
+		key string
+		old interface{}
+		new interface{}
+	}{
+		{"debug", old.Debug, conf.Debug},
+		{"file", old.File, conf.File},
+		{"period", old.Period, conf.Period},
+		{"retention", old.Retention, conf.Retention},
+		{"lateness", old.Lateness, conf.Lateness},
+		{"read_buffer_size", old.ReadBufferSize, conf.ReadBufferSize},
+		{"parser", old.Parser, conf.Parser},
+		{"history", old.History, conf.History},
+		{"notify", old.Notify, conf.Notify},
+		{"server", old.Server, conf.Server},
+		{"ui.headless", old.UI.Headless, conf.UI.Headless},
+	}
+	for _, r := range restart {
+		if !reflect.DeepEqual(r.old, r.new) {
+			changes.Restart = append(changes.Restart, r.key)
+		}
+	}
+
+	return cur, changes
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadOnlyAppliesRuntimeValues(t *testing.T) {
+	old := Default()
+	conf := Default()
+	conf.Alert.RequestsPerSecond.Threshold = 20
+	conf.Filter.ExcludeHosts = []string{"10.0.0.1"}
+	conf.Period = time.Minute
+	conf.Parser.Name = "jsonl"
+
+	cur, changes := reload(old, conf)
+
+	assert.Equal(t, []string{"filter", "alert"}, changes.Applied)
+	assert.Equal(t, []string{"period", "parser"}, changes.Restart)
+	assert.Equal(t, 20.0, cur.Alert.RequestsPerSecond.Threshold)
+	assert.Equal(t, []string{"10.0.0.1"}, cur.Filter.ExcludeHosts)
+	assert.Equal(t, old.Period, cur.Period)
+	assert.Equal(t, old.Parser, cur.Parser)
+}
+
+func TestReloadWithoutChanges(t *testing.T) {
+	_, changes := reload(Default(), Default())
+
+	assert.Empty(t, changes.Applied)
+	assert.Empty(t, changes.Restart)
+}
@@ -0,0 +1,17 @@
+package config
+
+// Server configures the http server exposing metrics
+type Server struct {
+	// Listen is the address the server listens on, "host:port" or ":port".
+	// The server is disabled if empty.
+	Listen string `json:"listen" yaml:"listen"`
+	// Debug exposes /debug/pprof and /debug/state
+	Debug bool `json:"debug" yaml:"debug"`
+	// Admin exposes POST /-/reload and lets silences be created and
+	// expired over http, there is no authentication
+	Admin bool `json:"admin" yaml:"admin"`
+}
+
+func (o Server) Default() Server {
+	return Server{}
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UI configures the terminal user interface
+type UI struct {
+	// Headless disables the terminal UI, metrics are then only
+	// served over http
+	Headless bool `json:"headless" yaml:"headless"`
+	// Refresh is the dashboards' refresh interval
+	Refresh time.Duration `json:"refresh" yaml:"refresh"`
+}
+
+func (o UI) Default() UI {
+	return UI{
+		Refresh: 250 * time.Millisecond,
+	}
+}
+
+// MarshalJSON writes durations in the go duration format
+func (o UI) MarshalJSON() ([]byte, error) {
+	type alias UI
+	return json.Marshal(struct {
+		alias
+		Refresh string `json:"refresh"`
+	}{alias(o), o.Refresh.String()})
+}
@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+)
+
+const (
+	BytesN string = "Bytes"
+)
+
+// BytesQuantiles are the response size quantiles shown by default
+var BytesQuantiles = []float64{0.5, 0.75, 0.9, 0.99}
+
+// Bytes measures the distribution of response sizes per period,
+// globally and per section. Each period has its own sketch so that
+// any range of retained periods can be merged.
+//
+// Memory is bounded by the sketches' number of bins, and by the retention.
+// Late responses are counted in Observed only: closed periods' sketches
+// never change so that they are shared with metrics instead of copied.
+type Bytes struct {
+	mutex sync.Mutex
+	// time last capture started
+	// capture are spaced of scrape period time
+	lastCapture time.Time
+	window      window // ongoing capture period
+	// sketches of the closed periods followed by the ongoing one,
+	// nil for a period without response. retention periods are kept.
+	retention  int
+	total      *Ring[*Sketch]
+	perSection map[string]*Ring[*Sketch]
+	// responses since the start
+	observed           Observed
+	observedPerSection map[string]Observed
+}
+
+// NewBytes creates a prober measuring response sizes per duration period.
+// Only the last retention periods are kept in memory.
+func NewBytes(duration time.Duration, retention int) *Bytes {
+	// This should have been validated before, should never happen
+	if duration < time.Second {
+		err := fmt.Errorf("critical, duration is below 1s, input : %s", duration)
+		panic(err)
+	}
+
+	return &Bytes{
+		window:             window{period: duration},
+		retention:          retention,
+		perSection:         make(map[string]*Ring[*Sketch]),
+		observedPerSection: make(map[string]Observed),
+	}
+}
+
+// newSeries creates a series holding the retained periods and the
+// ongoing one, it starts with n periods without response
+func (o *Bytes) newSeries(n int) *Ring[*Sketch] {
+	series := NewRing[*Sketch](o.retention + 1)
+	for i := 0; i < n; i++ {
+		series.Push(nil)
+	}
+	return series
+}
+
+// Update adds the trace's response size to its period's distribution,
+// entries are expected to be time-sorted in increasing order
+// (increasingly recent)
+func (o *Bytes) Update(t trace.Trace) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.total == nil {
+		o.total = o.newSeries(1)
+	}
+
+	o.advance(t.Date)
+
+	v := float64(t.Bytes)
+	o.observed = o.observed.add(v)
+	o.observedPerSection[t.Section] = o.observedPerSection[t.Section].add(v)
+
+	// Sections seen for the first time had no response in previous periods
+	series, ok := o.perSection[t.Section]
+	if !ok {
+		series = o.newSeries(o.total.Len())
+		o.perSection[t.Section] = series
+	}
+
+	if o.window.periodsAgo(t.Date) > 0 {
+		return
+	}
+	addLast(o.total, v)
+	addLast(series, v)
+}
+
+// addLast adds v to the sketch of the ongoing period
+func addLast(series *Ring[*Sketch], v float64) {
+	last := series.Len() - 1
+	s := series.At(last)
+	if s == nil {
+		s = NewDefaultSketch()
+		series.Set(last, s)
+	}
+	s.Add(v)
+}
+
+// Advance closes the periods ended before now, see Advancer
+func (o *Bytes) Advance(now time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.total == nil {
+		return
+	}
+	o.advance(now)
+}
+
+// advance closes all windows up to date's one, data arrive from
+// a more recent time window. Passed the retention, windows would be
+// overwritten anyway.
+func (o *Bytes) advance(date time.Time) {
+	closed := o.window.advance(date)
+	for i := min(closed, o.total.Cap()); i > 0; i-- {
+		o.total.Push(nil)
+		for _, series := range o.perSection {
+			series.Push(nil)
+		}
+	}
+	if closed > 0 {
+		o.lastCapture = o.window.lastStart()
+	}
+}
+
+// DeepCopy Returns a metric out of a deep copy of internal structures.
+// It is thread-safe though more expensive as locking Update on top of a copy
+func (o *Bytes) DeepCopy() Metric {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.Metric()
+}
+
+// Metric exposes the sketches of the retained periods, the ongoing one
+// is not part of it. Sketches are shared as they no longer change.
+func (o *Bytes) Metric() Metric {
+	var total []*Sketch
+	if o.total != nil {
+		total = o.total.Slice(o.total.Len() - 1)
+	}
+
+	labels := make(map[string][]*Sketch, len(o.perSection))
+	for section, series := range o.perSection {
+		labels[strings.Clone(section)] = series.Slice(series.Len() - 1)
+	}
+	observed := make(map[string]Observed, len(o.observedPerSection))
+	for section, v := range o.observedPerSection {
+		observed[strings.Clone(section)] = v
+	}
+
+	return Distribution{
+		time:           o.lastCapture.Unix(),
+		name:           BytesN,
+		quantiles:      BytesQuantiles,
+		total:          total,
+		labels:         labels,
+		observed:       o.observed,
+		labelsObserved: observed,
+	}
+}
+
+// Cardinality returns the number of sections
+func (o *Bytes) Cardinality() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.perSection)
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func bytesAt(unix int64, section string, bytes int) trace.Trace {
+	return trace.Trace{Date: time.Unix(unix, 0), Section: section, Bytes: uint(bytes)}
+}
+
+func TestBytesQuantiles(t *testing.T) {
+	p := NewBytes(10*time.Second, 100)
+	var q Quantiler = p.DeepCopy().(Distribution)
+	_, ok := q.Quantile(0.5)
+	assert.False(t, ok)
+
+	for i := 1; i <= 100; i++ {
+		p.Update(bytesAt(100, "/api", i*100))
+	}
+	// The period is ongoing
+	_, ok = p.DeepCopy().(Distribution).Quantile(0.5)
+	assert.False(t, ok)
+
+	p.Advance(time.Unix(110, 0))
+	q = p.DeepCopy().(Distribution)
+
+	assert.Equal(t, BytesQuantiles, q.Quantiles())
+	median, ok := q.Quantile(0.5)
+	assert.True(t, ok)
+	assert.InDelta(t, 5000, median, 100)
+	// Any quantile can be estimated
+	p75, ok := q.LabelQuantile("/api", 0.75)
+	assert.True(t, ok)
+	assert.InDelta(t, 7500, p75, 150)
+	_, ok = q.LabelQuantile("/help", 0.5)
+	assert.False(t, ok)
+}
+
+func TestBytesMergesPeriods(t *testing.T) {
+	p := NewBytes(10*time.Second, 100)
+
+	p.Update(bytesAt(100, "/api", 100))
+	p.Update(bytesAt(101, "/api", 100))
+	// idle period [110, 120)
+	p.Update(bytesAt(120, "/help", 1000))
+	p.Update(bytesAt(130, "/api", 10))
+	d := p.DeepCopy().(Distribution)
+
+	assert.Len(t, d.Total(), 3)
+	assert.Nil(t, d.Total()[1])
+	// Sections seen late are aligned
+	assert.Equal(t, []*Sketch{nil, nil, d.Total()[2]}, d.TypedLabels()["/help"])
+
+	assert.Equal(t, uint64(3), d.Merge(0).Count())
+	assert.Equal(t, uint64(1), d.Merge(1).Count())
+	assert.Equal(t, uint64(1), d.Merge(2).Count())
+	assert.InDelta(t, 1000, d.Merge(2).Quantile(0.5), 20)
+
+	api, ok := d.LabelMerge("/api", 0)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), api.Count())
+	_, ok = d.LabelMerge("/none", 0)
+	assert.False(t, ok)
+
+	// Observed counts every response since the start
+	assert.Equal(t, Observed{Count: 4, Sum: 1210}, d.Observed())
+	assert.Equal(t, Observed{Count: 3, Sum: 210}, d.LabelObserved("/api"))
+}
+
+func TestBytesKeepsRetainedPeriodsOnly(t *testing.T) {
+	p := NewBytes(10*time.Second, 3)
+
+	for date := int64(100); date < 200; date += 10 {
+		p.Update(bytesAt(date, "/", int(date)))
+	}
+	d := p.DeepCopy().(Distribution)
+
+	assert.Len(t, d.Total(), 3)
+	assert.Len(t, d.TypedLabels()["/"], 3)
+	assert.InDelta(t, 160, d.Merge(0).Quantile(0), 2)
+	assert.Equal(t, uint64(10), d.Observed().Count)
+}
+
+func TestBytesSharesClosedPeriods(t *testing.T) {
+	p := NewBytes(10*time.Second, 3)
+	p.Update(bytesAt(100, "/", 100))
+	p.Update(bytesAt(110, "/", 100))
+	d := p.DeepCopy().(Distribution)
+
+	// Late responses don't change closed periods
+	p.Update(bytesAt(105, "/", 100))
+	assert.Equal(t, uint64(1), d.Total()[0].Count())
+	assert.Equal(t, uint64(1), p.DeepCopy().(Distribution).Total()[0].Count())
+	assert.Equal(t, uint64(3), p.DeepCopy().(Distribution).Observed().Count)
+}
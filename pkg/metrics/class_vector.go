@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// ClassVector is a series of rates per class, globally and per label.
+// All series have the same length, a data point index is the same period.
+type ClassVector struct {
+	time   int64 // scrape time - unix seconds
+	name   string
+	period time.Duration
+	total  map[string][]float64
+	labels map[string]map[string][]float64
+}
+
+func (o ClassVector) String() string {
+	msg := fmt.Sprintf("Metric:\ntime: %s\nname: %s\n", time.Unix(o.time, 0), o.name)
+	for class, series := range o.total {
+		last := 0.0
+		if len(series) > 0 {
+			last = series[len(series)-1]
+		}
+		msg += fmt.Sprintf("%s: %f\n", class, last)
+	}
+
+	return msg
+}
+
+func (o ClassVector) ScrapeTime() int64 {
+	return o.time
+}
+
+func (o ClassVector) Name() string {
+	return o.name
+}
+
+// Period is the duration of a data point
+func (o ClassVector) Period() time.Duration {
+	return o.period
+}
+
+// Total returns one series per class
+func (o ClassVector) Total() map[string][]float64 {
+	return o.total
+}
+
+func (o ClassVector) Labels() interface{} {
+	return o.labels
+}
+
+// TypedLabels returns one series per class, per label
+func (o ClassVector) TypedLabels() map[string]map[string][]float64 {
+	return o.labels
+}
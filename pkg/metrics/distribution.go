@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// Distribution is a metric describing the distribution of a measured
+// value per period with quantile sketches, globally and per label.
+// Periods can be merged, any quantile can be estimated, quantiles are
+// the ones shown by default.
+type Distribution struct {
+	time      int64 // scrape time - unix seconds
+	name      string
+	quantiles []float64
+	// sketches per closed period, oldest first, nil for a period
+	// without measurement
+	total          []*Sketch
+	labels         map[string][]*Sketch
+	observed       Observed
+	labelsObserved map[string]Observed
+}
+
+// Observed counts and sums the values measured since the start
+type Observed struct {
+	Count uint64
+	Sum   float64
+}
+
+func (o Observed) add(v float64) Observed {
+	return Observed{Count: o.Count + 1, Sum: o.Sum + v}
+}
+
+func (o Distribution) String() string {
+	msg := fmt.Sprintf("Metric:\ntime: %s\nname: %s\nperiods: %d\ncount: %d\n",
+		time.Unix(o.time, 0), o.name, len(o.total), o.observed.Count)
+
+	return msg
+}
+
+func (o Distribution) ScrapeTime() int64 {
+	return o.time
+}
+
+func (o Distribution) Name() string {
+	return o.name
+}
+
+// Quantiles returns the quantiles shown by default
+func (o Distribution) Quantiles() []float64 {
+	return o.quantiles
+}
+
+// Quantile returns the q quantile of the values measured in the last
+// closed period, false if nothing was measured
+func (o Distribution) Quantile(q float64) (float64, bool) {
+	return lastSketchQuantile(o.total, q)
+}
+
+// LabelQuantile returns the q quantile of values measured for label in
+// the last closed period, false if nothing was measured
+func (o Distribution) LabelQuantile(label string, q float64) (float64, bool) {
+	return lastSketchQuantile(o.labels[label], q)
+}
+
+func lastSketchQuantile(series []*Sketch, q float64) (float64, bool) {
+	if len(series) == 0 {
+		return 0, false
+	}
+	s := series[len(series)-1]
+	if s == nil || s.Count() == 0 {
+		return 0, false
+	}
+	return s.Quantile(q), true
+}
+
+// Merge returns a sketch of all values measured in the last n periods,
+// every retained period if n <= 0
+func (o Distribution) Merge(n int) *Sketch {
+	return mergeLast(o.total, n)
+}
+
+// LabelMerge returns a sketch of the values measured for label in the
+// last n periods, every retained period if n <= 0. It returns false
+// if the label is unknown.
+func (o Distribution) LabelMerge(label string, n int) (*Sketch, bool) {
+	series, ok := o.labels[label]
+	if !ok {
+		return nil, false
+	}
+	return mergeLast(series, n), true
+}
+
+func mergeLast(series []*Sketch, n int) *Sketch {
+	if n <= 0 || n > len(series) {
+		n = len(series)
+	}
+
+	merged := NewDefaultSketch()
+	for _, s := range series[len(series)-n:] {
+		if err := merged.Merge(s); err != nil {
+			// Sketches are all created with the same parameters
+			panic(err)
+		}
+	}
+	return merged
+}
+
+// Observed returns the count and sum of all values measured since the start
+func (o Distribution) Observed() Observed {
+	return o.observed
+}
+
+// LabelObserved returns the count and sum of values measured for label
+// since the start
+func (o Distribution) LabelObserved(label string) Observed {
+	return o.labelsObserved[label]
+}
+
+// Total returns the sketch of every retained period, nil without measurement
+func (o Distribution) Total() []*Sketch {
+	return o.total
+}
+
+func (o Distribution) Labels() interface{} {
+	return o.labels
+}
+
+// TypedLabels returns the sketch of every retained period, per label
+func (o Distribution) TypedLabels() map[string][]*Sketch {
+	return o.labels
+}
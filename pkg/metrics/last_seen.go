@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+)
+
+const (
+	LastSeenN string = "LastSeen"
+)
+
+// LastSeen records the date of the latest trace globally, per section
+// and per host so that absent traffic can be detected
+type LastSeen struct {
+	mutex sync.Mutex
+	// start is the earliest of the first evaluation and the first trace,
+	// traffic never seen is considered seen last at start
+	start      time.Time
+	now        time.Time // clock time of the last evaluation
+	total      time.Time
+	perSection map[string]time.Time
+	perHost    map[string]time.Time
+}
+
+// NewLastSeen creates a prober recording when traffic was last seen
+func NewLastSeen() *LastSeen {
+	return &LastSeen{
+		perSection: make(map[string]time.Time),
+		perHost:    make(map[string]time.Time),
+	}
+}
+
+// Update records t's date, late traces don't move dates back
+func (o *LastSeen) Update(t trace.Trace) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.start.IsZero() || t.Date.Before(o.start) {
+		o.start = t.Date
+	}
+	if t.Date.After(o.now) {
+		o.now = t.Date
+	}
+	if t.Date.After(o.total) {
+		o.total = t.Date
+	}
+	if t.Date.After(o.perSection[t.Section]) {
+		o.perSection[t.Section] = t.Date
+	}
+	if t.Date.After(o.perHost[t.RemoteHost]) {
+		o.perHost[t.RemoteHost] = t.Date
+	}
+}
+
+// Advance sets the evaluation time, unlike other probers the first
+// evaluation starts the metric even without any trace so that traffic
+// which is never seen is detected
+func (o *LastSeen) Advance(now time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.start.IsZero() {
+		o.start = now
+	}
+	o.now = now
+}
+
+// DeepCopy Returns a metric out of a deep copy of internal structures.
+// It is thread-safe though more expensive as locking Update on top of a copy
+func (o *LastSeen) DeepCopy() Metric {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.Metric()
+}
+
+// Metric returns the last seen dates as of the last evaluation
+func (o *LastSeen) Metric() Metric {
+	sections := make(map[string]time.Time, len(o.perSection))
+	for k, v := range o.perSection {
+		sections[strings.Clone(k)] = v
+	}
+	hosts := make(map[string]time.Time, len(o.perHost))
+	for k, v := range o.perHost {
+		hosts[strings.Clone(k)] = v
+	}
+
+	return TimeVector{
+		time:     o.now,
+		name:     LastSeenN,
+		start:    o.start,
+		total:    o.total,
+		sections: sections,
+		hosts:    hosts,
+	}
+}
+
+// Cardinality returns the number of sections and hosts
+func (o *LastSeen) Cardinality() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.perSection) + len(o.perHost)
+}
+
+// TimeVector holds dates globally, per section and per host
+type TimeVector struct {
+	time     time.Time // scrape time
+	name     string
+	start    time.Time
+	total    time.Time
+	sections map[string]time.Time
+	hosts    map[string]time.Time
+}
+
+func (o TimeVector) String() string {
+	return fmt.Sprintf("Metric:\ntime: %s\nname: %s\ntotal: %s\n", o.time, o.name, o.total)
+}
+
+func (o TimeVector) ScrapeTime() int64 {
+	return o.time.Unix()
+}
+
+func (o TimeVector) Name() string {
+	return o.name
+}
+
+func (o TimeVector) Labels() interface{} {
+	return map[string]map[string]time.Time{
+		"section": o.sections,
+		"host":    o.hosts,
+	}
+}
+
+// Since returns how long ago, as of the scrape time, a trace of section
+// and of host was seen. Empty values select all of them, a section and
+// a host can't both be set. Traffic never seen is seen last at start.
+func (o TimeVector) Since(section, host string) time.Duration {
+	last := o.total
+	switch {
+	case section != "":
+		last = o.sections[section]
+	case host != "":
+		last = o.hosts[host]
+	}
+	if last.IsZero() {
+		last = o.start
+	}
+	return o.time.Sub(last)
+}
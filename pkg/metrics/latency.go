@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+)
+
+const (
+	LatencyN string = "Latency"
+)
+
+// LatencyQuantiles are the quantiles computed by the Latency prober
+var LatencyQuantiles = []float64{0.5, 0.9, 0.99}
+
+// Latency computes request latency quantiles, in seconds,
+// per period globally and per section.
+//
+// A period without latency measurement has all its quantiles set to 0.
+// Latencies of the ongoing period are counted in sketches so that memory
+// does not depend on the request rate.
+type Latency struct {
+	mutex sync.Mutex
+	// time last capture started
+	// capture are spaced of scrape period time
+	lastCapture time.Time
+	window      window // ongoing capture period
+	// latencies measured during the ongoing period
+	current           *Sketch
+	currentPerSection map[string]*Sketch
+	// closed periods' quantiles, one series per quantile, followed by
+	// the ongoing period's zero placeholder. retention periods are kept.
+	retention  int
+	total      []*Ring[float64]
+	perSection map[string][]*Ring[float64]
+}
+
+// NewLatency creates a prober computing latency quantiles per duration
+// period. Only the last retention periods are kept in memory.
+func NewLatency(duration time.Duration, retention int) *Latency {
+	// This should have been validated before, should never happen
+	if duration < time.Second {
+		err := fmt.Errorf("critical, duration is below 1s, input : %s", duration)
+		panic(err)
+	}
+
+	return &Latency{
+		window:            window{period: duration},
+		current:           NewDefaultSketch(),
+		currentPerSection: make(map[string]*Sketch),
+		retention:         retention,
+		total:             newQuantileSeries(retention, 1),
+		perSection:        make(map[string][]*Ring[float64]),
+	}
+}
+
+// newQuantileSeries creates one series per quantile holding the retained
+// periods and the ongoing one, each starts with n zeros
+func newQuantileSeries(retention int, n int) []*Ring[float64] {
+	series := make([]*Ring[float64], len(LatencyQuantiles))
+	for i := range series {
+		series[i] = NewRing[float64](retention + 1)
+		for j := 0; j < n; j++ {
+			series[i].Push(0)
+		}
+	}
+	return series
+}
+
+// Update records the trace's latency, it is assumed entries are time-sorted
+// in increasing order (increasingly recent)
+func (o *Latency) Update(t trace.Trace) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.advance(t.Date)
+
+	// Closed periods hold quantiles which cannot be updated,
+	// late measurements are ignored
+	if !t.HasLatency || o.window.periodsAgo(t.Date) > 0 {
+		return
+	}
+
+	l := t.Latency.Seconds()
+	o.current.Add(l)
+
+	s, ok := o.currentPerSection[t.Section]
+	if !ok {
+		s = NewDefaultSketch()
+		o.currentPerSection[t.Section] = s
+	}
+	s.Add(l)
+}
+
+// Advance closes the periods ended before now, see Advancer
+func (o *Latency) Advance(now time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.window.start.IsZero() {
+		return
+	}
+	o.advance(now)
+}
+
+// advance closes all windows up to date's one, data arrive from
+// a more recent time window. Passed the retention, windows would be
+// overwritten anyway.
+func (o *Latency) advance(date time.Time) {
+	closed := o.window.advance(date)
+	for i := min(closed, o.total[0].Cap()); i > 0; i-- {
+		o.close()
+	}
+	if closed > 0 {
+		o.lastCapture = o.window.lastStart()
+	}
+}
+
+// close sets the quantiles of the ongoing period in place of its
+// placeholder then opens a new period
+func (o *Latency) close() {
+	setQuantiles(o.total, o.current)
+	o.current = NewDefaultSketch()
+
+	for section, latencies := range o.currentPerSection {
+		series, ok := o.perSection[section]
+		if !ok {
+			// Sections seen for the first time had no measurement
+			// in previous windows
+			series = newQuantileSeries(o.retention, o.total[0].Len())
+			o.perSection[section] = series
+		}
+		setQuantiles(series, latencies)
+	}
+	o.currentPerSection = make(map[string]*Sketch, len(o.currentPerSection))
+
+	// Sections without measurement in the period keep their zero
+	// placeholder so that their last value is not mistaken for a current one
+	push := func(series []*Ring[float64]) {
+		for _, s := range series {
+			s.Push(0)
+		}
+	}
+	push(o.total)
+	for _, series := range o.perSection {
+		push(series)
+	}
+}
+
+// setQuantiles sets the quantiles of the sketch as the last value of series
+func setQuantiles(series []*Ring[float64], s *Sketch) {
+	for i, q := range LatencyQuantiles {
+		series[i].Set(series[i].Len()-1, s.Quantile(q))
+	}
+}
+
+// DeepCopy Returns a metric out of a deep copy of internal structures.
+// It is thread-safe though more expensive as locking Update on top of a copy
+func (o *Latency) DeepCopy() Metric {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.Metric()
+}
+
+// Metric outputs a metric measuring request latency quantiles
+// over the retained periods, the ongoing one is not part of it
+func (o *Latency) Metric() Metric {
+	newPerSection := make(map[string][][]float64, len(o.perSection))
+	for k, series := range o.perSection {
+		newPerSection[strings.Clone(k)] = copySeries(series)
+	}
+
+	return QuantileVector{
+		time:      o.lastCapture.Unix(),
+		name:      LatencyN,
+		quantiles: LatencyQuantiles,
+		total:     copySeries(o.total),
+		labels:    newPerSection,
+	}
+}
+
+func copySeries(series []*Ring[float64]) [][]float64 {
+	cp := make([][]float64, len(series))
+	for i, s := range series {
+		cp[i] = s.Slice(s.Len() - 1)
+	}
+	return cp
+}
+
+// Cardinality returns the number of sections
+func (o *Latency) Cardinality() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.perSection)
+}
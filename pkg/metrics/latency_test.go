@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func latencyAt(unix int64, section string, latency time.Duration) trace.Trace {
+	return trace.Trace{Date: time.Unix(unix, 0), Section: section, Latency: latency, HasLatency: true}
+}
+
+// assertSeries checks series values, sketches have a relative error
+func assertSeries(t *testing.T, want []float64, got []float64) {
+	t.Helper()
+	if !assert.Len(t, got, len(want)) {
+		return
+	}
+	for i := range want {
+		assert.InDelta(t, want[i], got[i], want[i]*0.02, "point %d", i)
+	}
+}
+
+func TestLatencyFillsIdlePeriodsWithZeros(t *testing.T) {
+	p := NewLatency(10*time.Second, 100)
+
+	p.Update(latencyAt(100, "/api", 100*time.Millisecond))
+	p.Update(latencyAt(105, "/api", 100*time.Millisecond))
+	// 2 idle periods: [110, 120), [120, 130)
+	p.Update(latencyAt(135, "/api", 300*time.Millisecond))
+	p.Update(latencyAt(140, "/api", 300*time.Millisecond))
+	m := p.DeepCopy().(QuantileVector)
+
+	for i := range LatencyQuantiles {
+		assertSeries(t, []float64{0.1, 0, 0, 0.3}, m.Total()[i])
+		assertSeries(t, []float64{0.1, 0, 0, 0.3}, m.TypedLabels()["/api"][i])
+	}
+	assert.Equal(t, int64(130), m.ScrapeTime())
+}
+
+func TestLatencyIgnoresLateMeasurements(t *testing.T) {
+	p := NewLatency(10*time.Second, 100)
+
+	p.Update(latencyAt(100, "/api", 100*time.Millisecond))
+	p.Update(latencyAt(112, "/api", 200*time.Millisecond))
+	// Closed periods' quantiles can't be updated
+	p.Update(latencyAt(105, "/api", 5*time.Second))
+	// No latency in the log
+	p.Update(trace.Trace{Date: time.Unix(115, 0), Section: "/api"})
+	p.Update(latencyAt(120, "/api", 100*time.Millisecond))
+	m := p.DeepCopy().(QuantileVector)
+
+	for i := range LatencyQuantiles {
+		assertSeries(t, []float64{0.1, 0.2}, m.Total()[i])
+	}
+}
+
+func TestLatencyAlignsLateSections(t *testing.T) {
+	p := NewLatency(10*time.Second, 100)
+
+	p.Update(latencyAt(100, "/api", 100*time.Millisecond))
+	p.Update(latencyAt(110, "/api", 100*time.Millisecond))
+	p.Update(latencyAt(125, "/help", 200*time.Millisecond))
+	p.Update(latencyAt(131, "/api", 100*time.Millisecond))
+	m := p.DeepCopy().(QuantileVector)
+
+	for i := range LatencyQuantiles {
+		assertSeries(t, []float64{0.1, 0.1, 0.2}, m.Total()[i])
+		assertSeries(t, []float64{0.1, 0.1, 0}, m.TypedLabels()["/api"][i])
+		assertSeries(t, []float64{0, 0, 0.2}, m.TypedLabels()["/help"][i])
+	}
+}
+
+func TestLatencyKeepsRetainedPeriodsOnly(t *testing.T) {
+	p := NewLatency(10*time.Second, 3)
+
+	for date := int64(100); date < 160; date += 10 {
+		p.Update(latencyAt(date, "/", time.Duration(date)*time.Millisecond))
+	}
+	m := p.DeepCopy().(QuantileVector)
+
+	assertSeries(t, []float64{0.12, 0.13, 0.14}, m.Total()[0])
+}
+
+func TestLatencyRetainsAsManyPeriodsAsOtherSeries(t *testing.T) {
+	l := NewLatency(10*time.Second, 3)
+	r := NewRequestsPerSecond(10*time.Second, 3)
+	c := NewStatusClasses(10*time.Second, 3)
+
+	for date := int64(100); date < 200; date += 10 {
+		tr := latencyAt(date, "/api", time.Millisecond)
+		tr.Status = 200
+		l.Update(tr)
+		r.Update(tr)
+		c.Update(tr)
+
+		n := len(r.DeepCopy().(CounterVector).Total())
+		for _, series := range l.DeepCopy().(QuantileVector).Total() {
+			assert.Len(t, series, n)
+		}
+		assert.Len(t, c.DeepCopy().(ClassVector).Total()["2xx"], n)
+	}
+
+	assert.Len(t, r.DeepCopy().(CounterVector).Total(), 3)
+	assert.Len(t, r.DeepCopy().(CounterVector).TypedLabels()["/api"], 3)
+	for _, series := range l.DeepCopy().(QuantileVector).TypedLabels()["/api"] {
+		assert.Len(t, series, 3)
+	}
+}
+
+func TestLatencyQuantiles(t *testing.T) {
+	p := NewLatency(10*time.Second, 100)
+	p.Update(latencyAt(100, "/api", 100*time.Millisecond))
+	p.Update(latencyAt(110, "/api", 200*time.Millisecond))
+	var q Quantiler = p.DeepCopy().(QuantileVector)
+
+	assert.Equal(t, LatencyQuantiles, q.Quantiles())
+	median, ok := q.Quantile(0.5)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.1, median, 0.002)
+	_, ok = q.LabelQuantile("/api", 0.75) // not computed
+	assert.False(t, ok)
+	_, ok = q.LabelQuantile("/help", 0.5)
+	assert.False(t, ok)
+}
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/julnicolas/httpmon/pkg/trace"
 )
 
@@ -18,6 +20,17 @@ type Prober interface {
 	// Keep in mind that calling this function may lock metric
 	// update during the copy
 	DeepCopy() Metric
+	// Cardinality returns the number of label values held, memory
+	// grows with it. It is thread-safe.
+	Cardinality() int
+}
+
+// Advancer is implemented by probers computing metrics over time periods
+type Advancer interface {
+	// Advance closes the periods ended before now so that periods
+	// without any trace are accounted for while traffic is silent.
+	// It does nothing until a first trace is seen.
+	Advance(now time.Time)
 }
 
 // Metric is a general Metrics interface, cast it to a concrete type
@@ -28,3 +41,17 @@ type Metric interface {
 	Name() string
 	Labels() interface{}
 }
+
+// Quantiler is a Metric describing the distribution of a measured value,
+// such as Latency's QuantileVector and Bytes' Distribution
+type Quantiler interface {
+	Metric
+	// Quantiles returns the quantiles computed or shown by default, in [0, 1]
+	Quantiles() []float64
+	// Quantile returns the q quantile of all measured values,
+	// false if there is none or if q is not computed
+	Quantile(q float64) (float64, bool)
+	// LabelQuantile returns the q quantile of values measured for label,
+	// false if there is none or if q is not computed
+	LabelQuantile(label string, q float64) (float64, bool)
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuantileVector is a series of quantile values, globally and per label.
+// Series are indexed like Quantiles(): Total()[i] is the series of
+// Quantiles()[i] values.
+type QuantileVector struct {
+	time      int64 // scrape time - unix seconds
+	name      string
+	quantiles []float64
+	total     [][]float64
+	labels    map[string][][]float64
+}
+
+func (o QuantileVector) String() string {
+	msg := fmt.Sprintf("Metric:\ntime: %s\nname: %s\n", time.Unix(o.time, 0), o.name)
+	for i, q := range o.quantiles {
+		last := 0.0
+		if len(o.total[i]) > 0 {
+			last = o.total[i][len(o.total[i])-1]
+		}
+		msg += fmt.Sprintf("p%g: %f\n", q*100, last)
+	}
+
+	return msg
+}
+
+func (o QuantileVector) ScrapeTime() int64 {
+	return o.time
+}
+
+func (o QuantileVector) Name() string {
+	return o.name
+}
+
+// Quantiles returns the computed quantiles, in [0, 1]
+func (o QuantileVector) Quantiles() []float64 {
+	return o.quantiles
+}
+
+// Quantile returns the last value of the q quantile series,
+// false if it is empty or if q is not computed
+func (o QuantileVector) Quantile(q float64) (float64, bool) {
+	return lastQuantile(o.quantiles, o.total, q)
+}
+
+// LabelQuantile returns the last value of the q quantile series of label,
+// false if it is empty or if q is not computed
+func (o QuantileVector) LabelQuantile(label string, q float64) (float64, bool) {
+	series, ok := o.labels[label]
+	if !ok {
+		return 0, false
+	}
+	return lastQuantile(o.quantiles, series, q)
+}
+
+func lastQuantile(quantiles []float64, series [][]float64, q float64) (float64, bool) {
+	for i, v := range quantiles {
+		if v == q && i < len(series) && len(series[i]) > 0 {
+			return series[i][len(series[i])-1], true
+		}
+	}
+	return 0, false
+}
+
+// Total returns one series per quantile
+func (o QuantileVector) Total() [][]float64 {
+	return o.total
+}
+
+func (o QuantileVector) Labels() interface{} {
+	return o.labels
+}
+
+// TypedLabels returns one series per quantile, per label
+func (o QuantileVector) TypedLabels() map[string][][]float64 {
+	return o.labels
+}
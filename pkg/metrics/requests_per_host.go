@@ -30,7 +30,10 @@ func (o *RequestsPerHost) Update(t trace.Trace) {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	o.lastScrape = t.Date
+	// Late traces must not move time backward
+	if t.Date.After(o.lastScrape) {
+		o.lastScrape = t.Date
+	}
 	o.total += 1
 	o.perHost[t.RemoteHost] += 1
 }
@@ -65,3 +68,11 @@ func (o *RequestsPerHost) Metric() Metric {
 		labels: o.perHost,
 	}
 }
+
+// Cardinality returns the number of hosts
+func (o *RequestsPerHost) Cardinality() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.perHost)
+}
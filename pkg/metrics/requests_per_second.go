@@ -19,13 +19,16 @@ type RequestsPerSecond struct {
 	// time last capture started
 	// capture are spaced of scrape period time
 	lastCapture time.Time
-	start       time.Time            // start of ongoing capture period
-	period      time.Duration        // Period is the collection period to compute
-	total       []float64            // data points, series of previous req/s values
-	perSection  map[string][]float64 // per-section series of previous req/s values
+	window      window // ongoing capture period
+	// retention is the number of closed periods kept per series
+	retention  int
+	total      *Ring[float64]            // data points, series of previous req/s values
+	perSection map[string]*Ring[float64] // per-section series of previous req/s values
 }
 
-func NewRequestsPerSecond(duration time.Duration) *RequestsPerSecond {
+// NewRequestsPerSecond creates a prober computing request rates per duration
+// period. Only the last retention periods are kept in memory.
+func NewRequestsPerSecond(duration time.Duration, retention int) *RequestsPerSecond {
 	// This should have been validated before, should never happen
 	if duration < time.Second {
 		err := fmt.Errorf("critical, duration is below 1s, input : %s", duration)
@@ -33,46 +36,111 @@ func NewRequestsPerSecond(duration time.Duration) *RequestsPerSecond {
 	}
 
 	return &RequestsPerSecond{
-		period:     duration,
-		perSection: make(map[string][]float64),
+		window:     window{period: duration},
+		retention:  retention,
+		perSection: make(map[string]*Ring[float64]),
 	}
 }
 
-// Update computes the request rate, it is assumed entries are time-sorted
-// in increasing order (increasingly recent)
+// newSeries creates a series holding the retained periods and the ongoing one
+func (o *RequestsPerSecond) newSeries() *Ring[float64] {
+	return NewRing[float64](o.retention + 1)
+}
+
+// Update computes the request rate, entries are expected to be time-sorted
+// in increasing order (increasingly recent).
+//
+// Late traces are attributed to the period they belong to, as long as
+// it is retained, older ones are ignored.
+//
+// Periods are consecutive, a period without request is a zero-valued
+// data point. All series, global and per section, have the same length
+// so that a data point index is the same period in every series.
 func (o *RequestsPerSecond) Update(t trace.Trace) {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	zero := time.Time{}
-	if o.start == zero {
-		o.start = t.Date
-		o.total = make([]float64, 1)
+	if o.total == nil {
+		o.total = o.newSeries()
+		o.total.Push(0)
 	}
 
-	// If true, data arrive from more recent time window
-	// so we need a new one to compute recent values
-	if t.Date.After(o.start.Add(o.period)) {
-		o.lastCapture = o.start
-		o.start = t.Date
-		o.total[len(o.total)-1] /= o.period.Seconds()
-		o.total = append(o.total, 0.0)
-
-		// The new slice reference would expire if using the value
-		// so let's make sure to store it in the object's map
-		for section := range o.perSection {
-			o.perSection[section][len(o.perSection[section])-1] /= o.period.Seconds()
-			o.perSection[section] = append(o.perSection[section], 0.0)
+	o.advance(t.Date)
+
+	// Sections seen for the first time had no request in previous windows
+	if _, ok := o.perSection[t.Section]; !ok {
+		series := o.newSeries()
+		for i := 0; i < o.total.Len(); i++ {
+			series.Push(0)
 		}
+		o.perSection[t.Section] = series
+	}
+
+	ago := o.window.periodsAgo(t.Date)
+	if ago >= o.total.Len() {
+		// Not retained anymore
+		return
+	}
+
+	// Count requests globally and per section on the trace's time window.
+	// Closed windows hold rates, not counts.
+	inc := 1.0
+	if ago > 0 {
+		inc /= o.window.period.Seconds()
+	}
+	add(o.total, ago, inc)
+	add(o.perSection[t.Section], ago, inc)
+}
+
+// Advance closes the periods ended before now, see Advancer
+func (o *RequestsPerSecond) Advance(now time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.total == nil {
+		return
+	}
+	o.advance(now)
+}
+
+// advance closes all windows up to date's one, data arrive from
+// a more recent time window. Passed the retention, windows would be
+// overwritten anyway.
+func (o *RequestsPerSecond) advance(date time.Time) {
+	closed := o.window.advance(date)
+	for i := min(closed, o.total.Cap()); i > 0; i-- {
+		o.closeWindow()
+	}
+	if closed > 0 {
+		o.lastCapture = o.window.lastStart()
 	}
+}
+
+// add adds v to the value ago points before the last one in series
+func add(series *Ring[float64], ago int, v float64) {
+	i := series.Len() - 1 - ago
+	series.Set(i, series.At(i)+v)
+}
 
-	// Count requests globally and per section on active time window
-	o.total[len(o.total)-1] += 1
-	if len(o.perSection[t.Section]) == 0 {
-		o.perSection[t.Section] = make([]float64, 1)
+// closeWindow turns the ongoing window's counts into rates
+// then opens a new window
+func (o *RequestsPerSecond) closeWindow() {
+	seconds := o.window.period.Seconds()
+	for _, series := range o.all() {
+		last := series.Len() - 1
+		series.Set(last, series.At(last)/seconds)
+		series.Push(0.0)
 	}
-	section := o.perSection[t.Section]
-	section[len(section)-1] += 1
+}
+
+// all returns the global series followed by every section's series
+func (o *RequestsPerSecond) all() []*Ring[float64] {
+	series := make([]*Ring[float64], 0, len(o.perSection)+1)
+	series = append(series, o.total)
+	for _, s := range o.perSection {
+		series = append(series, s)
+	}
+	return series
 }
 
 // DeepCopy Returns a metric out of a deep copy of internal structures.
@@ -81,21 +149,23 @@ func (o *RequestsPerSecond) DeepCopy() Metric {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
+	return o.Metric()
+}
+
+// Metric outputs a metric measuring the number or requests per second
+// over the retained periods.
+//
+// The last window is ongoing so its value is not an average yet,
+// it is not part of the metric.
+func (o *RequestsPerSecond) Metric() Metric {
 	var newTotal []float64
-	if len(o.total) > 0 {
-		newTotal = make([]float64, len(o.total)-1)
-		copy(newTotal, o.total)
+	if o.total != nil {
+		newTotal = o.total.Slice(o.total.Len() - 1)
 	}
 
 	newPerSection := make(map[string][]float64, len(o.perSection))
-	for k, vector := range o.perSection {
-		section := strings.Clone(k)
-
-		if len(o.perSection[k]) > 0 {
-			newVector := make([]float64, len(vector)-1)
-			copy(newVector, vector)
-			newPerSection[section] = newVector
-		}
+	for k, series := range o.perSection {
+		newPerSection[strings.Clone(k)] = series.Slice(series.Len() - 1)
 	}
 
 	return CounterVector{
@@ -106,16 +176,10 @@ func (o *RequestsPerSecond) DeepCopy() Metric {
 	}
 }
 
-// Metric outputs a metric measuring the number or requests per second
-func (o *RequestsPerSecond) Metric() Metric {
-	t := o.total
-	if len(o.total) > 0 {
-		t = t[:len(t)-1]
-	}
-	return CounterVector{
-		time:   o.lastCapture.Unix(),
-		name:   ReqsPerS,
-		total:  t,            // last window is ongoing so result is not an average yet
-		labels: o.perSection, // false here because last cell must not be considered
-	}
+// Cardinality returns the number of sections
+func (o *RequestsPerSecond) Cardinality() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.perSection)
 }
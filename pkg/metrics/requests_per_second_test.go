@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func reqAt(unix int64, section string) trace.Trace {
+	return trace.Trace{Date: time.Unix(unix, 0), Section: section}
+}
+
+func TestRequestsPerSecondFillsIdlePeriodsWithZeros(t *testing.T) {
+	p := NewRequestsPerSecond(10*time.Second, 100)
+
+	p.Update(reqAt(100, "/api"))
+	p.Update(reqAt(105, "/api"))
+	// 3 idle periods: [110, 120), [120, 130), [130, 140)
+	p.Update(reqAt(140, "/api"))
+	p.Update(reqAt(150, "/api"))
+	m := p.DeepCopy().(CounterVector)
+
+	assert.Equal(t, []float64{0.2, 0, 0, 0, 0.1}, m.Total())
+	assert.Equal(t, []float64{0.2, 0, 0, 0, 0.1}, m.TypedLabels()["/api"])
+	assert.Equal(t, int64(140), m.ScrapeTime())
+}
+
+func TestRequestsPerSecondAlignsLateSections(t *testing.T) {
+	p := NewRequestsPerSecond(10*time.Second, 100)
+
+	p.Update(reqAt(100, "/api"))
+	p.Update(reqAt(112, "/api"))
+	p.Update(reqAt(125, "/help"))
+	p.Update(reqAt(131, "/api"))
+	m := p.DeepCopy().(CounterVector)
+
+	assert.Equal(t, []float64{0.1, 0.1, 0.1}, m.Total())
+	assert.Equal(t, []float64{0.1, 0.1, 0}, m.TypedLabels()["/api"])
+	assert.Equal(t, []float64{0, 0, 0.1}, m.TypedLabels()["/help"])
+}
+
+func TestRequestsPerSecondWindowsAreHalfOpen(t *testing.T) {
+	p := NewRequestsPerSecond(10*time.Second, 100)
+
+	p.Update(reqAt(100, "/"))
+	p.Update(reqAt(109, "/"))
+	p.Update(reqAt(110, "/"))
+	p.Update(reqAt(120, "/"))
+	m := p.DeepCopy().(CounterVector)
+
+	assert.Equal(t, []float64{0.2, 0.1}, m.Total())
+}
+
+func TestRequestsPerSecondKeepsRetainedPeriodsOnly(t *testing.T) {
+	p := NewRequestsPerSecond(10*time.Second, 3)
+
+	for date := int64(100); date < 160; date += 10 {
+		p.Update(reqAt(date, "/"))
+	}
+	p.Update(reqAt(160, "/new"))
+	m := p.DeepCopy().(CounterVector)
+
+	assert.Equal(t, []float64{0.1, 0.1, 0.1}, m.Total())
+	assert.Equal(t, []float64{0.1, 0.1, 0.1}, m.TypedLabels()["/"])
+	assert.Equal(t, []float64{0, 0, 0}, m.TypedLabels()["/new"])
+}
+
+func TestRequestsPerSecondAttributesLateTracesToTheirPeriod(t *testing.T) {
+	p := NewRequestsPerSecond(10*time.Second, 100)
+
+	p.Update(reqAt(100, "/api"))
+	p.Update(reqAt(125, "/api"))
+	p.Update(reqAt(105, "/late"))
+	p.Update(reqAt(131, "/api"))
+	m := p.DeepCopy().(CounterVector)
+
+	assert.Equal(t, []float64{0.2, 0, 0.1}, m.Total())
+	assert.Equal(t, []float64{0.1, 0, 0.1}, m.TypedLabels()["/api"])
+	assert.Equal(t, []float64{0.1, 0, 0}, m.TypedLabels()["/late"])
+}
+
+func TestRequestsPerSecondAdvancesWhileSilent(t *testing.T) {
+	p := NewRequestsPerSecond(10*time.Second, 100)
+
+	// Nothing to close before a first trace
+	p.Advance(time.Unix(200, 0))
+	assert.Empty(t, p.DeepCopy().(CounterVector).Total())
+
+	p.Update(reqAt(100, "/api"))
+	p.Update(reqAt(105, "/api"))
+	p.Advance(time.Unix(135, 0))
+	m := p.DeepCopy().(CounterVector)
+
+	assert.Equal(t, []float64{0.2, 0, 0}, m.Total())
+	assert.Equal(t, []float64{0.2, 0, 0}, m.TypedLabels()["/api"])
+	assert.Equal(t, int64(120), m.ScrapeTime())
+
+	// Traces older than the clock are late ones
+	p.Update(reqAt(125, "/api"))
+	assert.Equal(t, []float64{0.2, 0, 0.1}, p.DeepCopy().(CounterVector).Total())
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import "fmt"
+
+// Ring is a fixed capacity series of values. Once full, pushing a value
+// overwrites the oldest one so that memory is bounded by the capacity:
+// 8 bytes per float64 value.
+type Ring[T any] struct {
+	values []T
+	head   int // index of the oldest value
+	size   int
+}
+
+// NewRing creates a ring able to retain capacity values
+func NewRing[T any](capacity int) *Ring[T] {
+	// This should have been validated before, should never happen
+	if capacity < 1 {
+		err := fmt.Errorf("critical, ring capacity must be positive, input : %d", capacity)
+		panic(err)
+	}
+
+	return &Ring[T]{
+		values: make([]T, capacity),
+	}
+}
+
+// Push appends v, dropping the oldest value if the ring is full
+func (o *Ring[T]) Push(v T) {
+	if o.size < len(o.values) {
+		o.values[(o.head+o.size)%len(o.values)] = v
+		o.size++
+		return
+	}
+
+	o.values[o.head] = v
+	o.head = (o.head + 1) % len(o.values)
+}
+
+// Len returns the number of retained values
+func (o *Ring[T]) Len() int {
+	return o.size
+}
+
+// Cap returns the maximum number of retained values
+func (o *Ring[T]) Cap() int {
+	return len(o.values)
+}
+
+// index returns the slice index of the i-th retained value, 0 being the oldest
+func (o *Ring[T]) index(i int) int {
+	if i < 0 || i >= o.size {
+		panic(fmt.Errorf("ring index %d out of range [0, %d)", i, o.size))
+	}
+	return (o.head + i) % len(o.values)
+}
+
+// At returns the i-th retained value, 0 being the oldest
+func (o *Ring[T]) At(i int) T {
+	return o.values[o.index(i)]
+}
+
+// Set sets the i-th retained value, 0 being the oldest
+func (o *Ring[T]) Set(i int, v T) {
+	o.values[o.index(i)] = v
+}
+
+// Slice returns a copy of the first n retained values, oldest first
+func (o *Ring[T]) Slice(n int) []T {
+	n = min(max(n, 0), o.size)
+	s := make([]T, n)
+	for i := 0; i < n; i++ {
+		s[i] = o.At(i)
+	}
+	return s
+}
+
+// Values returns a copy of all retained values, oldest first
+func (o *Ring[T]) Values() []T {
+	return o.Slice(o.size)
+}
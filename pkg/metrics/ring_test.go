@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingRetainsValuesUntilFull(t *testing.T) {
+	r := NewRing[float64](3)
+
+	assert.Equal(t, 0, r.Len())
+	assert.Equal(t, 3, r.Cap())
+	assert.Equal(t, []float64{}, r.Values())
+
+	r.Push(1)
+	r.Push(2)
+
+	assert.Equal(t, 2, r.Len())
+	assert.Equal(t, []float64{1, 2}, r.Values())
+	assert.Equal(t, []float64{1}, r.Slice(1))
+	assert.Equal(t, []float64{1, 2}, r.Slice(5))
+	assert.Equal(t, []float64{}, r.Slice(-1))
+}
+
+func TestRingOverwritesOldestValues(t *testing.T) {
+	r := NewRing[float64](3)
+
+	// the head wraps around twice
+	for v := 1; v <= 8; v++ {
+		r.Push(float64(v))
+	}
+
+	assert.Equal(t, 3, r.Len())
+	assert.Equal(t, 3, r.Cap())
+	assert.Equal(t, []float64{6, 7, 8}, r.Values())
+	assert.Equal(t, []float64{6, 7}, r.Slice(2))
+	assert.Equal(t, 6.0, r.At(0))
+	assert.Equal(t, 8.0, r.At(2))
+}
+
+func TestRingSetAfterWraparound(t *testing.T) {
+	r := NewRing[float64](3)
+	for v := 1; v <= 4; v++ {
+		r.Push(float64(v))
+	}
+
+	r.Set(0, 20)
+	r.Set(2, 40)
+	assert.Equal(t, []float64{20, 3, 40}, r.Values())
+
+	r.Push(5)
+	assert.Equal(t, []float64{3, 40, 5}, r.Values())
+}
+
+func TestRingOutOfRange(t *testing.T) {
+	r := NewRing[float64](3)
+	r.Push(1)
+
+	assert.Panics(t, func() { r.At(1) })
+	assert.Panics(t, func() { r.At(-1) })
+	assert.Panics(t, func() { r.Set(3, 0) })
+}
+
+func TestRingZeroCapacity(t *testing.T) {
+	assert.Panics(t, func() { NewRing[float64](0) })
+	assert.Panics(t, func() { NewRing[float64](-1) })
+}
+
+func TestRingCapacityOne(t *testing.T) {
+	r := NewRing[float64](1)
+	r.Push(1)
+	r.Push(2)
+
+	assert.Equal(t, 1, r.Len())
+	assert.Equal(t, []float64{2}, r.Values())
+}
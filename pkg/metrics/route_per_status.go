@@ -38,7 +38,10 @@ func (o *RoutePerStatus) Update(t trace.Trace) {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	o.lastScrape = t.Date
+	// Late traces must not move time backward
+	if t.Date.After(o.lastScrape) {
+		o.lastScrape = t.Date
+	}
 
 	m := o.perStatus[t.Status]
 	if m == nil {
@@ -81,6 +84,18 @@ func (o *RoutePerStatus) Metric() Metric {
 	}
 }
 
+// Cardinality returns the number of status code and section pairs
+func (o *RoutePerStatus) Cardinality() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	n := 0
+	for _, sections := range o.perStatus {
+		n += len(sections)
+	}
+	return n
+}
+
 type RoutePerStatusCounter struct {
 	time   int64 // scrape time - unix
 	name   string
@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Default sketch parameters, a 1% relative accuracy with 1024 bins
+// covers values from 1 to 10^8 without collapsing
+const (
+	DefaultSketchAccuracy float64 = 0.01
+	DefaultSketchBins     int     = 1024
+)
+
+// Sketch is a DDSketch, a quantile sketch with relative accuracy guarantees.
+//
+// Values are counted in logarithmically sized bins so that any quantile
+// is estimated within RelativeAccuracy of its true value. Memory is
+// bounded by the maximum number of bins: when it is reached, the lowest
+// bins are collapsed which only degrades the accuracy of low quantiles.
+//
+// Sketches with the same accuracy can be merged, e.g. to aggregate
+// periods or sections. Sketch is not thread-safe.
+//
+// See https://arxiv.org/abs/1908.10693
+type Sketch struct {
+	accuracy float64
+	gamma    float64
+	logGamma float64
+	maxBins  int
+	bins     map[int]uint64 // counts of positive values per bin index
+	zeros    uint64         // count of values too small to be indexed
+	count    uint64
+	sum      float64
+	min      float64
+	max      float64
+}
+
+// minIndexable is the smallest value given a bin,
+// smaller values are counted as zeros
+const minIndexable float64 = 1e-9
+
+// NewSketch creates a sketch estimating quantiles within accuracy (0 < accuracy < 1)
+// of their value, using at most maxBins bins
+func NewSketch(accuracy float64, maxBins int) *Sketch {
+	// This should have been validated before, should never happen
+	if accuracy <= 0 || accuracy >= 1 || maxBins < 1 {
+		err := fmt.Errorf("critical, invalid sketch parameters accuracy: %f, bins: %d", accuracy, maxBins)
+		panic(err)
+	}
+
+	gamma := (1 + accuracy) / (1 - accuracy)
+	return &Sketch{
+		accuracy: accuracy,
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		maxBins:  maxBins,
+		bins:     make(map[int]uint64),
+		min:      math.Inf(1),
+		max:      math.Inf(-1),
+	}
+}
+
+// NewDefaultSketch creates a sketch using default parameters
+func NewDefaultSketch() *Sketch {
+	return NewSketch(DefaultSketchAccuracy, DefaultSketchBins)
+}
+
+// Add counts a value, negative values are counted as zeros
+func (o *Sketch) Add(v float64) {
+	v = max(v, 0)
+
+	if v < minIndexable {
+		o.zeros++
+	} else {
+		o.bins[o.index(v)]++
+		o.collapse()
+	}
+
+	o.count++
+	o.sum += v
+	o.min = min(o.min, v)
+	o.max = max(o.max, v)
+}
+
+// Merge adds other's values to the sketch. Both sketches must share
+// the same accuracy.
+func (o *Sketch) Merge(other *Sketch) error {
+	if other == nil || other.count == 0 {
+		return nil
+	}
+	if other.gamma != o.gamma {
+		return fmt.Errorf("cannot merge sketches of accuracy %f and %f", o.accuracy, other.accuracy)
+	}
+
+	for i, c := range other.bins {
+		o.bins[i] += c
+	}
+	o.zeros += other.zeros
+	o.count += other.count
+	o.sum += other.sum
+	o.min = min(o.min, other.min)
+	o.max = max(o.max, other.max)
+
+	for len(o.bins) > o.maxBins {
+		o.collapse()
+	}
+	return nil
+}
+
+// Quantile returns an estimation of the q quantile (0 <= q <= 1),
+// 0 if the sketch is empty
+func (o *Sketch) Quantile(q float64) float64 {
+	if o.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return o.min
+	}
+	if q >= 1 {
+		return o.max
+	}
+
+	rank := uint64(q * float64(o.count-1))
+	if rank < o.zeros {
+		return 0
+	}
+
+	seen := o.zeros
+	for _, i := range o.sortedIndexes() {
+		seen += o.bins[i]
+		if seen > rank {
+			// Estimations can't be out of the observed range
+			return min(max(o.value(i), o.min), o.max)
+		}
+	}
+
+	return o.max
+}
+
+// Count returns the number of values added
+func (o *Sketch) Count() uint64 {
+	return o.count
+}
+
+// Sum returns the sum of values added
+func (o *Sketch) Sum() float64 {
+	return o.sum
+}
+
+// Bins returns the number of bins in use
+func (o *Sketch) Bins() int {
+	return len(o.bins)
+}
+
+// Copy returns a deep copy of the sketch
+func (o *Sketch) Copy() *Sketch {
+	n := new(Sketch)
+	*n = *o
+	n.bins = make(map[int]uint64, len(o.bins))
+	for i, c := range o.bins {
+		n.bins[i] = c
+	}
+
+	return n
+}
+
+// index returns the index of the bin counting v
+func (o *Sketch) index(v float64) int {
+	return int(math.Ceil(math.Log(v) / o.logGamma))
+}
+
+// value returns the estimation of values counted in bin i
+func (o *Sketch) value(i int) float64 {
+	return 2 * math.Pow(o.gamma, float64(i)) / (o.gamma + 1)
+}
+
+// collapse merges the lowest bin into the next one while there are
+// too many bins
+func (o *Sketch) collapse() {
+	if len(o.bins) <= o.maxBins {
+		return
+	}
+
+	lowest, next := math.MaxInt, math.MaxInt
+	for i := range o.bins {
+		switch {
+		case i < lowest:
+			lowest, next = i, lowest
+		case i < next:
+			next = i
+		}
+	}
+
+	o.bins[next] += o.bins[lowest]
+	delete(o.bins, lowest)
+}
+
+func (o *Sketch) sortedIndexes() []int {
+	indexes := make([]int, 0, len(o.bins))
+	for i := range o.bins {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+	return indexes
+}
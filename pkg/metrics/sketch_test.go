@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketchQuantilesAreWithinRelativeAccuracy(t *testing.T) {
+	s := NewSketch(0.01, 2048)
+	for i := 1; i <= 10000; i++ {
+		s.Add(float64(i))
+	}
+
+	for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+		exact := math.Floor(q*9999) + 1
+		assert.InEpsilon(t, exact, s.Quantile(q), 0.01, "quantile %f", q)
+	}
+	assert.Equal(t, uint64(10000), s.Count())
+	assert.Equal(t, 1.0, s.Quantile(0))
+	assert.Equal(t, 10000.0, s.Quantile(1))
+}
+
+func TestSketchMergeEqualsSingleSketch(t *testing.T) {
+	all := NewDefaultSketch()
+	even, odd := NewDefaultSketch(), NewDefaultSketch()
+	for i := 0; i < 1000; i++ {
+		all.Add(float64(i))
+		if i%2 == 0 {
+			even.Add(float64(i))
+		} else {
+			odd.Add(float64(i))
+		}
+	}
+
+	assert.NoError(t, even.Merge(odd))
+
+	assert.Equal(t, all.Count(), even.Count())
+	assert.Equal(t, all.Sum(), even.Sum())
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		assert.Equal(t, all.Quantile(q), even.Quantile(q))
+	}
+}
+
+func TestSketchMemoryIsBounded(t *testing.T) {
+	s := NewSketch(0.01, 64)
+	for v := 1.0; v < 1e12; v *= 1.1 {
+		s.Add(v)
+	}
+
+	assert.LessOrEqual(t, s.Bins(), 64)
+	// high quantiles are kept accurate when collapsing
+	assert.InEpsilon(t, 1e12/1.1, s.Quantile(1-1e-9), 0.2)
+}
+
+func TestSketchMergeRejectsDifferentAccuracies(t *testing.T) {
+	a, b := NewSketch(0.01, 10), NewSketch(0.02, 10)
+	b.Add(1)
+
+	assert.Error(t, a.Merge(b))
+}
@@ -0,0 +1,194 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+)
+
+const (
+	StatusClassesN string = "StatusClasses"
+)
+
+// StatusClasses computes request rates per status class (2xx, 5xx...)
+// and period, globally and per section.
+//
+// Unlike RoutePerStatus which counts since the start, a period only
+// accounts for its requests so that ratios can be computed over recent
+// periods. Periods and late traces are handled like RequestsPerSecond.
+type StatusClasses struct {
+	mutex sync.Mutex
+	// time last capture started
+	// capture are spaced of scrape period time
+	lastCapture time.Time
+	window      window // ongoing capture period
+	// retention is the number of closed periods kept per series
+	retention int
+	// points is the length of every series, ongoing period included
+	points     int
+	total      map[string]*Ring[float64]            // per class series of req/s
+	perSection map[string]map[string]*Ring[float64] // per section, per class series of req/s
+}
+
+// NewStatusClasses creates a prober computing request rates per status class
+// and duration period. Only the last retention periods are kept in memory.
+func NewStatusClasses(duration time.Duration, retention int) *StatusClasses {
+	// This should have been validated before, should never happen
+	if duration < time.Second {
+		err := fmt.Errorf("critical, duration is below 1s, input : %s", duration)
+		panic(err)
+	}
+
+	return &StatusClasses{
+		window:     window{period: duration},
+		retention:  retention,
+		total:      make(map[string]*Ring[float64]),
+		perSection: make(map[string]map[string]*Ring[float64]),
+	}
+}
+
+// StatusClass returns the class of a status code, e.g. 5xx for 503
+func StatusClass(status uint) string {
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// Update counts t in its status class, entries are expected to be time-sorted
+// in increasing order (increasingly recent).
+//
+// Series of classes and sections seen for the first time are zero-filled
+// so that a data point index is the same period in every series.
+func (o *StatusClasses) Update(t trace.Trace) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.points == 0 {
+		o.points = 1
+	}
+
+	o.advance(t.Date)
+
+	sections, ok := o.perSection[t.Section]
+	if !ok {
+		sections = make(map[string]*Ring[float64])
+		o.perSection[t.Section] = sections
+	}
+	class := StatusClass(t.Status)
+	total, section := o.series(o.total, class), o.series(sections, class)
+
+	ago := o.window.periodsAgo(t.Date)
+	if ago >= o.points {
+		// Not retained anymore
+		return
+	}
+
+	// Closed windows hold rates, not counts
+	inc := 1.0
+	if ago > 0 {
+		inc /= o.window.period.Seconds()
+	}
+	add(total, ago, inc)
+	add(section, ago, inc)
+}
+
+// Advance closes the periods ended before now, see Advancer
+func (o *StatusClasses) Advance(now time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.points == 0 {
+		return
+	}
+	o.advance(now)
+}
+
+// advance closes all windows up to date's one
+func (o *StatusClasses) advance(date time.Time) {
+	closed := o.window.advance(date)
+	for i := min(closed, o.retention+1); i > 0; i-- {
+		o.closeWindow()
+	}
+	if closed > 0 {
+		o.lastCapture = o.window.lastStart()
+	}
+}
+
+// series returns the series of class in classes, creating it if needed
+func (o *StatusClasses) series(classes map[string]*Ring[float64], class string) *Ring[float64] {
+	s, ok := classes[class]
+	if !ok {
+		s = NewRing[float64](o.retention + 1)
+		for i := 0; i < o.points; i++ {
+			s.Push(0)
+		}
+		classes[class] = s
+	}
+	return s
+}
+
+// closeWindow turns the ongoing window's counts into rates
+// then opens a new window
+func (o *StatusClasses) closeWindow() {
+	seconds := o.window.period.Seconds()
+	closeSeries := func(classes map[string]*Ring[float64]) {
+		for _, series := range classes {
+			last := series.Len() - 1
+			series.Set(last, series.At(last)/seconds)
+			series.Push(0.0)
+		}
+	}
+
+	closeSeries(o.total)
+	for _, classes := range o.perSection {
+		closeSeries(classes)
+	}
+	o.points = min(o.points+1, o.retention+1)
+}
+
+// DeepCopy Returns a metric out of a deep copy of internal structures.
+// It is thread-safe though more expensive as locking Update on top of a copy
+func (o *StatusClasses) DeepCopy() Metric {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.Metric()
+}
+
+// Metric outputs the request rates per status class over the retained
+// periods, the ongoing one is not part of the metric.
+func (o *StatusClasses) Metric() Metric {
+	closed := func(classes map[string]*Ring[float64]) map[string][]float64 {
+		c := make(map[string][]float64, len(classes))
+		for class, series := range classes {
+			c[class] = series.Slice(series.Len() - 1)
+		}
+		return c
+	}
+
+	labels := make(map[string]map[string][]float64, len(o.perSection))
+	for section, classes := range o.perSection {
+		labels[strings.Clone(section)] = closed(classes)
+	}
+
+	return ClassVector{
+		time:   o.lastCapture.Unix(),
+		name:   StatusClassesN,
+		period: o.window.period,
+		total:  closed(o.total),
+		labels: labels,
+	}
+}
+
+// Cardinality returns the number of section and status class pairs
+func (o *StatusClasses) Cardinality() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	n := 0
+	for _, classes := range o.perSection {
+		n += len(classes)
+	}
+	return n
+}
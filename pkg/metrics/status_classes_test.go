@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusClassesAreWindowed(t *testing.T) {
+	p := NewStatusClasses(10*time.Second, 100)
+	req := func(unix int64, section string, status uint) {
+		p.Update(trace.Trace{Date: time.Unix(unix, 0), Section: section, Status: status})
+	}
+
+	req(100, "/api", 200)
+	req(101, "/api", 503)
+	req(112, "/help", 404) // 4xx of the first period is zero-filled
+	req(113, "/api", 500)
+	req(125, "/api", 200)
+	m := p.DeepCopy().(ClassVector)
+
+	assert.Equal(t, map[string][]float64{
+		"2xx": {0.1, 0},
+		"4xx": {0, 0.1},
+		"5xx": {0.1, 0.1},
+	}, m.Total())
+	assert.Equal(t, map[string][]float64{"2xx": {0.1, 0}, "5xx": {0.1, 0.1}}, m.TypedLabels()["/api"])
+	assert.Equal(t, map[string][]float64{"4xx": {0, 0.1}}, m.TypedLabels()["/help"])
+	assert.Equal(t, int64(110), m.ScrapeTime())
+	assert.Equal(t, 3, p.Cardinality())
+}
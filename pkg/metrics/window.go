@@ -0,0 +1,45 @@
+package metrics
+
+import "time"
+
+// window splits time into consecutive collection periods of equal
+// duration, aligned on the first observed date
+type window struct {
+	start  time.Time     // start of ongoing capture period, zero until the first date
+	period time.Duration // Period is the collection period to compute
+}
+
+// advance moves the ongoing period forward so that it contains date.
+// It returns the number of periods closed in the process, periods without
+// any date included. Dates older than the ongoing period do not move it.
+func (o *window) advance(date time.Time) int {
+	zero := time.Time{}
+	if o.start == zero {
+		o.start = date
+		return 0
+	}
+
+	if date.Before(o.start.Add(o.period)) {
+		return 0
+	}
+
+	closed := int(date.Sub(o.start) / o.period)
+	o.start = o.start.Add(time.Duration(closed) * o.period)
+	return closed
+}
+
+// periodsAgo returns how many periods before the ongoing one date is,
+// 0 if date is in the ongoing period or later
+func (o *window) periodsAgo(date time.Time) int {
+	if !date.Before(o.start) {
+		return 0
+	}
+
+	// Round up, the ongoing period's start belongs to it
+	return int((o.start.Sub(date) + o.period - 1) / o.period)
+}
+
+// lastStart returns the start of the last closed period
+func (o *window) lastStart() time.Time {
+	return o.start.Add(-o.period)
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/backend"
+	"github.com/julnicolas/httpmon/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Dispatcher turns alert state transitions into firing and resolved
+// messages queued for every receiver
+type Dispatcher struct {
+	mutex  sync.Mutex
+	queues []*Queue
+	starts map[alert.NameT]time.Time // notification time of firing alerts
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func NewDispatcher(conf config.Notify) *Dispatcher {
+	o := &Dispatcher{
+		starts: make(map[alert.NameT]time.Time),
+	}
+	for _, w := range conf.Webhooks {
+		o.add(NewWebhook(w.URL), conf)
+	}
+	for _, e := range conf.Exec {
+		o.add(NewExec(e.Command), conf)
+	}
+	for _, s := range conf.SMTP {
+		o.add(NewSMTP(s), conf)
+	}
+	return o
+}
+
+// add sends notifications to n too
+func (o *Dispatcher) add(n Notifier, conf config.Notify) {
+	o.queues = append(o.queues, NewQueue(n, conf.QueueSize, conf.Timeout, conf.Retries, conf.Backoff))
+}
+
+// Enabled returns true if there is at least a receiver
+func (o *Dispatcher) Enabled() bool {
+	return len(o.queues) > 0
+}
+
+// Start delivers notifications in the background until Close is called
+func (o *Dispatcher) Start(ctx context.Context) {
+	ctx, o.cancel = context.WithCancel(ctx)
+	for _, q := range o.queues {
+		o.wg.Add(1)
+		go func(q *Queue) {
+			defer o.wg.Done()
+			q.Run(ctx)
+		}(q)
+	}
+}
+
+// Notify queues a message if t is a firing or resolved transition,
+// it never blocks. Muted alerts don't fire, they fire once unmuted if still
+// active. Resolved messages are only sent for alerts which fired.
+func (o *Dispatcher) Notify(t backend.AlertStateTransition) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	name, state := t.Alert.Name(), t.Alert.State()
+	at := time.Unix(t.Time, 0).UTC()
+	start, fired := o.starts[name]
+
+	var m Message
+	switch {
+	case state == alert.Active && !fired && !t.Muted():
+		o.starts[name] = at
+		m = newMessage(Firing, t.Alert.Labels(), t.Alert.Description(), at, time.Time{})
+	case state != alert.Active && fired:
+		m = newMessage(Resolved, t.Alert.Labels(), t.Alert.Description(), start, at)
+		delete(o.starts, name)
+	default:
+		return
+	}
+
+	for _, q := range o.queues {
+		if !q.Push(m) {
+			logrus.Warnf("notify - %s queue is full, %s notification of %q dropped", q.notifier.Name(), m.Status, name)
+		}
+	}
+}
+
+// Close stops deliveries, queued notifications are dropped
+func (o *Dispatcher) Close() {
+	if o.cancel != nil {
+		o.cancel()
+	}
+	o.wg.Wait()
+}
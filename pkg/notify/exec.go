@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Exec runs a command per message, the message is written as json on its
+// stdin and summarised in HTTPMON_ALERT_* environment variables
+type Exec struct {
+	command []string // program then arguments
+}
+
+func NewExec(command []string) *Exec {
+	return &Exec{command: command}
+}
+
+// Name is the program only, arguments may hold secrets
+func (o *Exec) Name() string {
+	return "exec " + o.command[0]
+}
+
+// Send runs the command, it fails if it exits with a non zero status
+func (o *Exec) Send(ctx context.Context, m Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, o.command[0], o.command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(), env(m)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// env returns the environment variables describing m's alert
+func env(m Message) []string {
+	a := m.Alerts[0]
+	vars := []string{
+		"HTTPMON_ALERT_NAME=" + a.Labels["alertname"],
+		"HTTPMON_ALERT_STATE=" + a.Status,
+		"HTTPMON_ALERT_DESCRIPTION=" + a.Annotations["description"],
+		"HTTPMON_ALERT_STARTS_AT=" + a.StartsAt.Format(time.RFC3339),
+	}
+	if !a.EndsAt.IsZero() {
+		vars = append(vars, "HTTPMON_ALERT_ENDS_AT="+a.EndsAt.Format(time.RFC3339))
+	}
+	return vars
+}
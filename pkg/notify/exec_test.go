@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecGetsMessageOnStdinAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "notify.sh")
+	out := filepath.Join(dir, "out")
+	data := "#!/bin/sh\ncat > \"$1.json\"\necho \"$HTTPMON_ALERT_NAME $HTTPMON_ALERT_STATE\" > \"$1\"\n"
+	assert.NoError(t, os.WriteFile(script, []byte(data), 0o700))
+
+	m := newMessage(Firing, alert.Labels{alert.AlertName: "busy"}, "active if busy", time.Unix(100, 0), time.Time{})
+	assert.NoError(t, NewExec([]string{script, out}).Send(context.Background(), m))
+
+	env, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "busy firing\n", string(env))
+
+	stdin, err := os.ReadFile(out + ".json")
+	assert.NoError(t, err)
+	var received Message
+	assert.NoError(t, json.Unmarshal(stdin, &received))
+	assert.Equal(t, Firing, received.Status)
+}
+
+func TestExecFails(t *testing.T) {
+	m := newMessage(Firing, alert.Labels{alert.AlertName: "busy"}, "", time.Unix(100, 0), time.Time{})
+	err := NewExec([]string{"sh", "-c", "echo broken >&2; exit 3"}).Send(context.Background(), m)
+
+	assert.EqualError(t, err, "exit status 3: broken")
+}
+
+func TestExecNameHidesArguments(t *testing.T) {
+	o := NewExec([]string{"notify.sh", "--token", "abc"})
+	assert.Equal(t, "exec notify.sh", o.Name())
+}
@@ -0,0 +1,93 @@
+// Package notify sends alert state transitions to external receivers
+// such as webhooks.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+)
+
+const (
+	Firing   string = "firing"
+	Resolved string = "resolved"
+)
+
+// Notifier delivers messages to a receiver
+type Notifier interface {
+	// Name identifies the receiver in logs
+	Name() string
+	// Send delivers m, it must return when ctx is done
+	Send(ctx context.Context, m Message) error
+}
+
+// Message is a notification in the Prometheus Alertmanager webhook format,
+// so that receivers written for Alertmanager can be reused
+type Message struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// Alert is an alert of a Message
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"` // zero while firing
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// newMessage creates the message of a single alert, messages are not grouped.
+// Labels are the alert's, alertname is the name of its rule.
+func newMessage(status string, l alert.Labels, description string, startsAt, endsAt time.Time) Message {
+	labels := maps.Clone(map[string]string(l))
+	annotations := map[string]string{"description": description}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, labels[name]))
+	}
+	set := "{" + strings.Join(pairs, ", ") + "}"
+
+	h := fnv.New64a()
+	h.Write([]byte(set))
+
+	return Message{
+		Version:           "4",
+		GroupKey:          "{}:" + set,
+		Status:            status,
+		Receiver:          "httpmon",
+		GroupLabels:       labels,
+		CommonLabels:      labels,
+		CommonAnnotations: annotations,
+		Alerts: []Alert{{
+			Status:      status,
+			Labels:      labels,
+			Annotations: annotations,
+			StartsAt:    startsAt,
+			EndsAt:      endsAt,
+			Fingerprint: fmt.Sprintf("%016x", h.Sum64()),
+		}},
+	}
+}
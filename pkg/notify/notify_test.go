@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/backend"
+	"github.com/julnicolas/httpmon/pkg/config"
+	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAlert struct {
+	state alert.State
+}
+
+func (o fakeAlert) Name() alert.NameT               { return `busy{section="/api"}` }
+func (o fakeAlert) State() alert.State              { return o.state }
+func (o fakeAlert) Eval(metrics.Metric) alert.State { return o.state }
+func (o fakeAlert) EvalTime() time.Time             { return time.Time{} }
+func (o fakeAlert) Description() string             { return "active if busy" }
+func (o fakeAlert) Labels() alert.Labels {
+	return alert.Labels{alert.AlertName: "busy", "section": "/api"}
+}
+func (o fakeAlert) DeepCopy() alert.Alert { return o }
+
+func transition(prev, state alert.State, unix int64) backend.AlertStateTransition {
+	return backend.AlertStateTransition{Prev: prev, Alert: fakeAlert{state}, Time: unix}
+}
+
+func TestWebhookReceivesFiringAndResolved(t *testing.T) {
+	received := make(chan Message, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m Message
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		received <- m
+	}))
+	defer srv.Close()
+
+	conf := config.Notify{}.Default()
+	conf.Webhooks = []config.Webhook{{URL: srv.URL}}
+	o := NewDispatcher(conf)
+	o.Start(context.Background())
+	defer o.Close()
+
+	o.Notify(transition(alert.Inactive, alert.Pending, 100)) // not notified
+	o.Notify(transition(alert.Pending, alert.Active, 110))
+	o.Notify(transition(alert.Active, alert.Inactive, 130))
+
+	firing, resolved := <-received, <-received
+	assert.Equal(t, Firing, firing.Status)
+	// Fanned out alerts are identified by their labels
+	assert.Equal(t, map[string]string{"alertname": "busy", "section": "/api"}, firing.Alerts[0].Labels)
+	assert.Equal(t, firing.Alerts[0].Labels, firing.CommonLabels)
+	assert.Equal(t, `{}:{alertname="busy", section="/api"}`, firing.GroupKey)
+	assert.Equal(t, "active if busy", firing.Alerts[0].Annotations["description"])
+	assert.Equal(t, time.Unix(110, 0).UTC(), firing.Alerts[0].StartsAt)
+	assert.Equal(t, Resolved, resolved.Status)
+	assert.Equal(t, time.Unix(110, 0).UTC(), resolved.Alerts[0].StartsAt)
+	assert.Equal(t, time.Unix(130, 0).UTC(), resolved.Alerts[0].EndsAt)
+}
+
+func TestWebhookNameHidesTokens(t *testing.T) {
+	o := NewWebhook("https://hooks.example.com/services/T000/B000/token?key=abc")
+	assert.Equal(t, "webhook https://hooks.example.com/<secret>", o.Name())
+}
+
+// recorder records sent messages
+type recorder chan Message
+
+func (o recorder) Name() string { return "recorder" }
+func (o recorder) Send(ctx context.Context, m Message) error {
+	o <- m
+	return nil
+}
+
+func TestMutedAlertsDontFire(t *testing.T) {
+	received := make(recorder, 3)
+	o := NewDispatcher(config.Notify{}.Default())
+	o.add(received, config.Notify{}.Default())
+	o.Start(context.Background())
+	defer o.Close()
+
+	silenced := func(prev, state alert.State, unix int64) backend.AlertStateTransition {
+		t := transition(prev, state, unix)
+		t.Silenced = true
+		return t
+	}
+	o.Notify(silenced(alert.Pending, alert.Active, 110))
+	// Fires once the silence ends
+	o.Notify(transition(alert.Active, alert.Active, 120))
+	o.Notify(silenced(alert.Active, alert.Inactive, 130))
+
+	firing, resolved := <-received, <-received
+	assert.Equal(t, Firing, firing.Status)
+	assert.Equal(t, time.Unix(120, 0).UTC(), firing.Alerts[0].StartsAt)
+	assert.Equal(t, Resolved, resolved.Status)
+
+	o.Notify(silenced(alert.Pending, alert.Active, 140))
+	o.Notify(silenced(alert.Active, alert.Inactive, 150))
+	assert.Never(t, func() bool { return len(received) > 0 }, 50*time.Millisecond, time.Millisecond)
+}
+
+// flakyNotifier fails fails times then succeeds, blocking until unblock is closed
+type flakyNotifier struct {
+	fails    int
+	attempts int
+	unblock  chan struct{}
+}
+
+func (o *flakyNotifier) Name() string { return "flaky" }
+func (o *flakyNotifier) Send(ctx context.Context, m Message) error {
+	<-o.unblock
+	o.attempts++
+	if o.attempts <= o.fails {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func TestQueueRetries(t *testing.T) {
+	n := &flakyNotifier{fails: 2, unblock: make(chan struct{})}
+	close(n.unblock)
+	q := NewQueue(n, 1, time.Second, 2, time.Millisecond)
+
+	assert.NoError(t, q.send(context.Background(), Message{}))
+	assert.Equal(t, 3, n.attempts)
+
+	n.attempts, n.fails = 0, 5
+	assert.Error(t, q.send(context.Background(), Message{}))
+	assert.Equal(t, 3, n.attempts)
+}
+
+func TestQueuePushNeverBlocks(t *testing.T) {
+	n := &flakyNotifier{unblock: make(chan struct{})}
+	q := NewQueue(n, 1, time.Second, 0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+	defer close(n.unblock)
+
+	// The first message is being sent, the second one waits
+	assert.True(t, q.Push(Message{}))
+	assert.Eventually(t, func() bool { return len(q.messages) == 0 }, time.Second, time.Millisecond)
+	assert.True(t, q.Push(Message{}))
+	assert.False(t, q.Push(Message{}))
+	assert.Equal(t, uint64(1), q.Dropped())
+}
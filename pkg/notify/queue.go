@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Queue delivers messages to a notifier from a bounded queue.
+// Failed deliveries are retried with an exponential backoff.
+type Queue struct {
+	notifier Notifier
+	messages chan Message
+	timeout  time.Duration // of an attempt
+	retries  uint
+	backoff  time.Duration // before the first retry
+	dropped  atomic.Uint64
+}
+
+func NewQueue(n Notifier, size uint, timeout time.Duration, retries uint, backoff time.Duration) *Queue {
+	return &Queue{
+		notifier: n,
+		messages: make(chan Message, size),
+		timeout:  timeout,
+		retries:  retries,
+		backoff:  backoff,
+	}
+}
+
+// Push queues m without blocking so that a slow receiver never slows
+// alert evaluation down. It returns false if the queue is full, m is
+// dropped then.
+func (o *Queue) Push(m Message) bool {
+	select {
+	case o.messages <- m:
+		return true
+	default:
+		o.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped returns the number of messages dropped because the queue was full
+func (o *Queue) Dropped() uint64 {
+	return o.dropped.Load()
+}
+
+// Run delivers queued messages until ctx is done
+func (o *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case m := <-o.messages:
+			if err := o.send(ctx, m); err != nil && ctx.Err() == nil {
+				logrus.Errorf("notify - %s: %s, notification dropped", o.notifier.Name(), err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// send delivers m, retrying on failure
+func (o *Queue) send(ctx context.Context, m Message) error {
+	backoff := o.backoff
+	var err error
+	for attempt := uint(0); attempt <= o.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+		}
+
+		if err = o.attempt(ctx, m); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (o *Queue) attempt(ctx context.Context, m Message) error {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	return o.notifier.Send(ctx, m)
+}
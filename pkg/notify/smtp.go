@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/config"
+)
+
+const (
+	// DefaultSubject is the default email subject template
+	DefaultSubject string = `[{{ .Status }}] {{ index .CommonLabels "alertname" }}`
+	// DefaultBody is the default email body template
+	DefaultBody string = `{{ range .Alerts }}Alert {{ index .Labels "alertname" }} is {{ .Status }}
+{{ index .Annotations "description" }}
+Started at {{ .StartsAt.Format "2006-01-02 15:04:05 MST" }}
+{{- if not .EndsAt.IsZero }}, resolved at {{ .EndsAt.Format "2006-01-02 15:04:05 MST" }}{{ end }}
+{{ end }}`
+)
+
+// SMTP emails messages, subject and body are text templates
+// executed over the Message
+type SMTP struct {
+	addr    string // host:port
+	from    string
+	to      []string
+	auth    smtp.Auth // nil without username
+	subject *template.Template
+	body    *template.Template
+}
+
+// NewSMTP creates an email notifier, conf's templates must have been validated
+func NewSMTP(conf config.SMTP) *SMTP {
+	subject, body := conf.Subject, conf.Body
+	if subject == "" {
+		subject = DefaultSubject
+	}
+	if body == "" {
+		body = DefaultBody
+	}
+
+	o := &SMTP{
+		addr:    conf.Addr,
+		from:    conf.From,
+		to:      conf.To,
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+	if conf.Username != "" {
+		host, _, _ := net.SplitHostPort(conf.Addr)
+		o.auth = smtp.PlainAuth("", conf.Username, conf.Password, host)
+	}
+	return o
+}
+
+func (o *SMTP) Name() string {
+	return "smtp " + o.addr
+}
+
+// Send emails m, using STARTTLS if the server supports it
+func (o *SMTP) Send(ctx context.Context, m Message) error {
+	data, err := o.email(m)
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", o.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(o.addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if o.auth != nil {
+		if err := c.Auth(o.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(o.from); err != nil {
+		return err
+	}
+	for _, to := range o.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// email returns the email of m, headers then body
+func (o *SMTP) email(m Message) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := o.subject.Execute(&subject, m); err != nil {
+		return nil, fmt.Errorf("subject template - %w", err)
+	}
+	if err := o.body.Execute(&body, m); err != nil {
+		return nil, fmt.Errorf("body template - %w", err)
+	}
+
+	var email bytes.Buffer
+	fmt.Fprintf(&email, "From: %s\r\n", o.from)
+	fmt.Fprintf(&email, "To: %s\r\n", strings.Join(o.to, ", "))
+	fmt.Fprintf(&email, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject.String()))
+	fmt.Fprintf(&email, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&email, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&email, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	email.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+
+	return email.Bytes(), nil
+}
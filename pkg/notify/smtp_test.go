@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTP accepts one email and sends its data on the returned channel
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	emails := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 fake")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				emails <- data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return l.Addr().String(), emails
+}
+
+func TestSMTPSendsTemplatedEmail(t *testing.T) {
+	addr, emails := fakeSMTP(t)
+	o := NewSMTP(config.SMTP{
+		Addr:    addr,
+		From:    "httpmon@example.com",
+		To:      []string{"oncall@example.com"},
+		Subject: `{{ .Status }}: {{ index .CommonLabels "alertname" }}`,
+	})
+
+	m := newMessage(Resolved, alert.Labels{alert.AlertName: "busy"}, "active if busy", time.Unix(100, 0).UTC(), time.Unix(160, 0).UTC())
+	assert.NoError(t, o.Send(context.Background(), m))
+
+	email := <-emails
+	assert.Contains(t, email, "Subject: resolved: busy\r\n")
+	assert.Contains(t, email, "To: oncall@example.com\r\n")
+	assert.Contains(t, email, "Alert busy is resolved\r\nactive if busy\r\n")
+	assert.Contains(t, email, "Started at 1970-01-01 00:01:40 UTC, resolved at 1970-01-01 00:02:40 UTC\r\n")
+}
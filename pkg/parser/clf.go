@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+)
+
+// CLFTimeLayout is the time layout used by the NCSA log formats
+// e.g. 10/Oct/2000:13:55:36 -0700
+const CLFTimeLayout string = "02/Jan/2006:15:04:05 -0700"
+
+// CLF parses lines written in the NCSA Common Log Format:
+//
+//	host ident authuser [date] "request" status bytes
+type CLF struct{}
+
+// NewCLF creates a new Common Log Format parser
+func NewCLF() *CLF {
+	return &CLF{}
+}
+
+// Parse creates a Trace representing an HTTP call
+func (o *CLF) Parse(raw string) (trace.Trace, error) {
+	const nbFields int = 7
+
+	fields, err := ncsaFields(raw)
+	if err != nil {
+		return trace.Trace{}, err
+	}
+	if len(fields) != nbFields {
+		return trace.Trace{}, fmt.Errorf("clf parse error - expected %d fields, had %d", nbFields, len(fields))
+	}
+
+	return parseNCSA(fields)
+}
+
+// Combined parses lines written in the NCSA Combined Log Format,
+// that is the Common Log Format followed by the referer and user-agent:
+//
+//	host ident authuser [date] "request" status bytes "referer" "user-agent"
+//
+// Trailing fields, often appended by nginx configurations, are ignored.
+type Combined struct{}
+
+// NewCombined creates a new Combined Log Format parser
+func NewCombined() *Combined {
+	return &Combined{}
+}
+
+// Parse creates a Trace representing an HTTP call
+func (o *Combined) Parse(raw string) (trace.Trace, error) {
+	const nbFields int = 9
+
+	fields, err := ncsaFields(raw)
+	if err != nil {
+		return trace.Trace{}, err
+	}
+	if len(fields) < nbFields {
+		return trace.Trace{}, fmt.Errorf("combined parse error - expected %d fields, had %d", nbFields, len(fields))
+	}
+
+	t, err := parseNCSA(fields)
+	if err != nil {
+		return trace.Trace{}, err
+	}
+	if err := parseReferer(&t, fields[7]); err != nil {
+		return trace.Trace{}, err
+	}
+	if err := parseUserAgent(&t, fields[8]); err != nil {
+		return trace.Trace{}, err
+	}
+
+	return t, nil
+}
+
+// parseNCSA fills a trace from the fields shared by the NCSA formats
+func parseNCSA(fields []string) (trace.Trace, error) {
+	t := trace.Trace{}
+	if err := parseCLFDate(&t, fields[3]); err != nil {
+		return trace.Trace{}, err
+	}
+	if err := parseRemoteHost(&t, fields[0]); err != nil {
+		return trace.Trace{}, err
+	}
+	if err := parseRFC931(&t, fields[1]); err != nil {
+		return trace.Trace{}, err
+	}
+	if fields[2] != "-" {
+		if err := parseAuthUser(&t, fields[2]); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+	if err := parseRequest(&t, fields[4]); err != nil {
+		return trace.Trace{}, err
+	}
+	if err := parseStatus(&t, fields[5]); err != nil {
+		return trace.Trace{}, err
+	}
+	// No body has been sent
+	if fields[6] != "-" {
+		if err := parseBytes(&t, fields[6]); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+
+	return t, nil
+}
+
+// ncsaFields splits a line on spaces. Values between square brackets
+// or double quotes are kept whole, without their delimiters.
+// Backslash-escaped characters are kept as is within quotes.
+func ncsaFields(raw string) ([]string, error) {
+	fields := make([]string, 0, 9)
+
+	for i := 0; i < len(raw); {
+		switch raw[i] {
+		case ' ', '\t':
+			i++
+		case '[':
+			end := strings.IndexByte(raw[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' at column %d", i)
+			}
+			fields = append(fields, raw[i+1:i+end])
+			i += end + 1
+		case '"':
+			end := quoteEnd(raw, i+1)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '\"' at column %d", i)
+			}
+			fields = append(fields, raw[i+1:end])
+			i = end + 1
+		default:
+			end := strings.IndexAny(raw[i:], " \t")
+			if end < 0 {
+				end = len(raw) - i
+			}
+			fields = append(fields, raw[i:i+end])
+			i += end
+		}
+	}
+
+	return fields, nil
+}
+
+// quoteEnd returns the index of the first unescaped double quote
+// found from start, -1 if there is none.
+func quoteEnd(raw string, start int) int {
+	for i := start; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func parseCLFDate(t *trace.Trace, field string) error {
+	if t == nil {
+		return fmt.Errorf("nil receiver")
+	}
+
+	date, err := time.Parse(CLFTimeLayout, field)
+	if err != nil {
+		return err
+	}
+	t.Date = date
+	return nil
+}
+
+func parseReferer(t *trace.Trace, field string) error {
+	if t == nil {
+		return fmt.Errorf("nil receiver")
+	}
+	if field == "-" {
+		return nil
+	}
+	t.Referer = field
+	return nil
+}
+
+func parseUserAgent(t *trace.Trace, field string) error {
+	if t == nil {
+		return fmt.Errorf("nil receiver")
+	}
+	if field == "-" {
+		return nil
+	}
+	t.UserAgent = field
+	return nil
+}
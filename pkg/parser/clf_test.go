@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCLFParsesApacheExample(t *testing.T) {
+	p := NewCLF()
+	raw := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`
+
+	tr, err := p.Parse(raw)
+
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC).Equal(tr.Date))
+	assert.Equal(t, "127.0.0.1", tr.RemoteHost)
+	assert.Equal(t, "frank", tr.AuthUser)
+	assert.Equal(t, "GET", tr.Method)
+	assert.Equal(t, "/apache_pb.gif", tr.Section)
+	assert.Equal(t, uint(200), tr.Status)
+	assert.Equal(t, uint(2326), tr.Bytes)
+}
+
+func TestCLFRejectsCombinedLines(t *testing.T) {
+	p := NewCLF()
+	raw := `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" 304 - "-" "curl/8.0"`
+
+	_, err := p.Parse(raw)
+
+	assert.Error(t, err)
+}
+
+func TestCombinedParsesRefererAndUserAgent(t *testing.T) {
+	p := NewCombined()
+	raw := `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "POST /api/user HTTP/1.1" 201 - "http://example.com/\"start\"" "Mozilla/4.08 [en] (Win98; I ;Nav)"`
+
+	tr, err := p.Parse(raw)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", tr.AuthUser)
+	assert.Equal(t, "/api", tr.Section)
+	assert.Equal(t, uint(0), tr.Bytes)
+	assert.Equal(t, `http://example.com/\"start\"`, tr.Referer)
+	assert.Equal(t, "Mozilla/4.08 [en] (Win98; I ;Nav)", tr.UserAgent)
+}
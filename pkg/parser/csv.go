@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"encoding/csv"
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,65 +10,118 @@ import (
 	"github.com/julnicolas/httpmon/pkg/trace"
 )
 
+// Known CSV columns, all other columns are kept as trace attributes
+const (
+	csvRemoteHost string = "remotehost"
+	csvRFC931     string = "rfc931"
+	csvAuthUser   string = "authuser"
+	csvDate       string = "date"
+	csvRequest    string = "request"
+	csvStatus     string = "status"
+	csvBytes      string = "bytes"
+	csvLatency    string = "latency"
+)
+
+// csvRequired lists the columns a header must define
+var csvRequired = []string{csvDate, csvRequest, csvStatus}
+
 // CSV parses csv-formatted strings representing
 // http calls. It returns a well formed Trace.
+//
+// Columns are mapped by their header name, in any order. The header is
+// either the first line of the stream or supplied at creation.
 type CSV struct {
-	delimiter string
-	// true if header has been parsed and validated.
+	delimiter rune
+	// header lists column names in order, nil until the header
+	// has been parsed and validated.
 	// It means content is ready to be parsed.
-	validHeader bool
+	header []string
+	// columns maps known column names to their index
+	columns map[string]int
 }
 
-// NewCSV creates a new CSV parser
+// NewCSV creates a new comma separated CSV parser
+// reading its header from the stream
 func NewCSV() *CSV {
-	return &CSV{delimiter: ","}
+	return &CSV{delimiter: ','}
 }
 
-// value returns a csv field value with all formatting removed
-func (o *CSV) value(field string) string {
-	return strings.Trim(field, "\"")
+// NewCustomCSV creates a new CSV parser splitting fields on delimiter.
+// If header is empty it is read from the stream, otherwise
+// every line of the stream is data.
+func NewCustomCSV(delimiter rune, header []string) (*CSV, error) {
+	o := &CSV{delimiter: delimiter}
+	if delimiter == 0 || delimiter == '"' || delimiter == '\r' || delimiter == '\n' {
+		return nil, fmt.Errorf("csv - invalid delimiter %q", delimiter)
+	}
+
+	if len(header) > 0 {
+		if err := o.setHeader(header); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
 }
 
-// Header parses the header, returns an error if it doesn't follow the
+// record splits a csv line into fields, removing all formatting
+func (o *CSV) record(raw string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(raw))
+	r.Comma = o.delimiter
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	fields, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("csv parse error - %w", err)
+	}
+	return fields, nil
+}
+
+// validateHeader parses the header, returns an error if it doesn't follow the
 // expected format
 //
 // If the header is valid, it returns the parser.HeaderData error value
 func (o *CSV) validateHeader(header string) error {
-	// Number of expected CSV fields
-	const nbFields uint = 7
-
-	fields := strings.Split(strings.ToLower(header), o.delimiter)
-	if len(fields) != 7 {
-		return fmt.Errorf("csv parse error - expected %d fields, add %d", nbFields, len(fields))
+	fields, err := o.record(header)
+	if err != nil {
+		return err
 	}
 
-	fmtErr := "missing required field %s"
-	if o.value(fields[0]) != "remotehost" {
-		return fmt.Errorf(fmtErr, fields[0])
-	}
-	if o.value(fields[1]) != "rfc931" {
-		return fmt.Errorf(fmtErr, fields[1])
-	}
-	if o.value(fields[2]) != "authuser" {
-		return fmt.Errorf(fmtErr, fields[2])
-	}
-	if o.value(fields[3]) != "date" {
-		return fmt.Errorf(fmtErr, fields[3])
-	}
-	if o.value(fields[4]) != "request" {
-		return fmt.Errorf(fmtErr, fields[4])
-	}
-	if o.value(fields[5]) != "status" {
-		return fmt.Errorf(fmtErr, fields[5])
+	if err := o.setHeader(fields); err != nil {
+		return err
 	}
-	if o.value(fields[6]) != "bytes" {
-		return fmt.Errorf(fmtErr, fields[6])
+
+	return ErrHeaderData
+}
+
+// setHeader maps column names onto their index, the header is valid
+// if all required columns are defined once
+func (o *CSV) setHeader(fields []string) error {
+	columns := make(map[string]int, len(fields))
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		name := strings.ToLower(strings.TrimSpace(field))
+		if name == "" {
+			return fmt.Errorf("csv parse error - column %d has no name", i)
+		}
+		if _, ok := columns[name]; ok {
+			return fmt.Errorf("csv parse error - duplicated column %s", name)
+		}
+		columns[name] = i
+		header[i] = name
+	}
+
+	for _, name := range csvRequired {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("csv parse error - missing required field %s", name)
+		}
 	}
 
 	// All checks have passed so the stream can be parsed
-	o.validHeader = true
-
-	return ErrHeaderData
+	o.header = header
+	o.columns = columns
+	return nil
 }
 
 // Parse creates a Trace representing an HTTP call
@@ -77,43 +131,52 @@ func (o *CSV) validateHeader(header string) error {
 func (o *CSV) Parse(raw string) (trace.Trace, error) {
 	// Intent header validation until it is validated
 	// then proceed with data parsing on next calls
-	if !o.validHeader {
+	if o.header == nil {
 		return trace.Trace{}, o.validateHeader(raw)
 	}
 
-	const nbFields uint = 7
-
-	fields := strings.Split(raw, o.delimiter)
-	if len(fields) != 7 {
-		return trace.Trace{}, fmt.Errorf("csv parse error - expected %d fields, add %d", nbFields, len(fields))
-	}
-
-	t := trace.Trace{}
-	if err := parseDate(&t, o.value(fields[3])); err != nil {
-		return trace.Trace{}, err
-	}
-	if err := parseRemoteHost(&t, o.value(fields[0])); err != nil {
-		return trace.Trace{}, err
-	}
-	if err := parseAuthUser(&t, o.value(fields[2])); err != nil {
-		return trace.Trace{}, err
-	}
-	if err := parseRFC931(&t, o.value(fields[1])); err != nil {
-		return trace.Trace{}, err
-	}
-	if err := parseRequest(&t, o.value(fields[4])); err != nil {
+	fields, err := o.record(raw)
+	if err != nil {
 		return trace.Trace{}, err
 	}
-	if err := parseStatus(&t, o.value(fields[5])); err != nil {
-		return trace.Trace{}, err
+	if len(fields) != len(o.header) {
+		return trace.Trace{}, fmt.Errorf("csv parse error - expected %d fields, had %d", len(o.header), len(fields))
 	}
-	if err := parseBytes(&t, o.value(fields[6])); err != nil {
-		return trace.Trace{}, err
+
+	t := trace.Trace{}
+	for i, name := range o.header {
+		if err := o.parseColumn(&t, name, fields[i]); err != nil {
+			return trace.Trace{}, err
+		}
 	}
 
 	return t, nil
 }
 
+// parseColumn fills the trace field matching a column
+func (o *CSV) parseColumn(t *trace.Trace, name, field string) error {
+	switch name {
+	case csvDate:
+		return parseDate(t, field)
+	case csvRemoteHost:
+		return parseRemoteHost(t, field)
+	case csvAuthUser:
+		return parseAuthUser(t, field)
+	case csvRFC931:
+		return parseRFC931(t, field)
+	case csvRequest:
+		return parseRequest(t, field)
+	case csvStatus:
+		return parseStatus(t, field)
+	case csvBytes:
+		return parseBytes(t, field)
+	case csvLatency:
+		return optional(parseLatency)(t, field)
+	default:
+		return parseAttribute(t, name, field)
+	}
+}
+
 func parseDate(t *trace.Trace, field string) error {
 	if t == nil {
 		return fmt.Errorf("nil receiver")
@@ -212,7 +275,12 @@ func parseSection(t *trace.Trace, field string) error {
 	if field == "" {
 		return fmt.Errorf("empty url path")
 	}
+	if !strings.HasPrefix(field, "/") {
+		return fmt.Errorf("url path must start with '/', received %q", field)
+	}
 
+	// The query string is not part of the path
+	field, _, _ = strings.Cut(field, "?")
 	t.Section = "/" + strings.Split(field, "/")[1]
 	return nil
 }
@@ -259,3 +327,55 @@ func parseBytes(t *trace.Trace, field string) error {
 	t.Bytes = uint(bytes)
 	return nil
 }
+
+// parseLatency parses a go duration such as 120ms or a number of seconds
+func parseLatency(t *trace.Trace, field string) error {
+	if t == nil {
+		return fmt.Errorf("nil receiver")
+	}
+
+	if seconds, err := strconv.ParseFloat(field, 64); err == nil {
+		return setLatency(t, time.Duration(seconds*float64(time.Second)))
+	}
+
+	d, err := time.ParseDuration(field)
+	if err != nil {
+		return fmt.Errorf("invalid latency %q", field)
+	}
+	return setLatency(t, d)
+}
+
+// latencySetter parses an integer latency expressed in unit
+func latencySetter(unit time.Duration) setter {
+	return func(t *trace.Trace, field string) error {
+		if t == nil {
+			return fmt.Errorf("nil receiver")
+		}
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid latency %q", field)
+		}
+		return setLatency(t, time.Duration(n)*unit)
+	}
+}
+
+func setLatency(t *trace.Trace, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("negative latency %s", d)
+	}
+	t.Latency = d
+	t.HasLatency = true
+	return nil
+}
+
+// parseAttribute keeps a value the trace has no field for
+func parseAttribute(t *trace.Trace, name, field string) error {
+	if t == nil {
+		return fmt.Errorf("nil receiver")
+	}
+	if t.Attributes == nil {
+		t.Attributes = make(map[string]string)
+	}
+	t.Attributes[name] = field
+	return nil
+}
@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVParsesDefaultLayout(t *testing.T) {
+	p := NewCSV()
+
+	_, headerErr := p.Parse(`"remotehost","rfc931","authuser","date","request","status","bytes"`)
+	tr, err := p.Parse(`"10.0.0.2","-","apache",1549573860,"GET /api/user HTTP/1.0",200,1234`)
+
+	assert.Equal(t, ErrHeaderData, headerErr)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1549573860, 0), tr.Date)
+	assert.Equal(t, "10.0.0.2", tr.RemoteHost)
+	assert.Equal(t, "apache", tr.AuthUser)
+	assert.Equal(t, "/api", tr.Section)
+	assert.Equal(t, uint(200), tr.Status)
+	assert.Equal(t, uint(1234), tr.Bytes)
+	assert.Nil(t, tr.Attributes)
+}
+
+func TestCSVMapsColumnsByNameAndKeepsExtraColumns(t *testing.T) {
+	p := NewCSV()
+
+	_, headerErr := p.Parse(`status,date,"user agent",request`)
+	tr, err := p.Parse(`404,1549573860,"Mozilla/5.0 (X11, Linux)","GET /help HTTP/1.1"`)
+
+	assert.Equal(t, ErrHeaderData, headerErr)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(404), tr.Status)
+	assert.Equal(t, "/help", tr.Section)
+	assert.Equal(t, map[string]string{"user agent": "Mozilla/5.0 (X11, Linux)"}, tr.Attributes)
+}
+
+func TestCSVRejectsHeaderWithoutRequiredColumns(t *testing.T) {
+	p := NewCSV()
+
+	_, err := p.Parse(`remotehost,date,request`)
+
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrHeaderData, err)
+}
+
+func TestCustomCSVParsesTSVWithSuppliedHeader(t *testing.T) {
+	p, err := NewCustomCSV('\t', []string{"date", "request", "status"})
+	assert.NoError(t, err)
+
+	tr, err := p.Parse("1549573860\tPOST /api/user HTTP/1.0\t201")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "POST", tr.Method)
+	assert.Equal(t, uint(201), tr.Status)
+}
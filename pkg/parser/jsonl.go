@@ -0,0 +1,246 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+)
+
+// JSONLKeys maps trace fields onto the JSON keys holding them.
+// Nested objects can be reached with a dotted key such as "http.status".
+type JSONLKeys struct {
+	Host      string
+	User      string
+	Timestamp string
+	Method    string
+	Path      string
+	Protocol  string
+	Status    string
+	Bytes     string
+	Latency   string
+}
+
+// DefaultJSONLKeys returns the keys used when none are configured
+func DefaultJSONLKeys() JSONLKeys {
+	return JSONLKeys{
+		Host:      "host",
+		User:      "user",
+		Timestamp: "timestamp",
+		Method:    "method",
+		Path:      "path",
+		Protocol:  "protocol",
+		Status:    "status",
+		Bytes:     "bytes",
+		Latency:   "latency",
+	}
+}
+
+// Override sets the key of a trace field by its name (host, user, timestamp,
+// method, path, protocol, status, bytes or latency).
+func (o *JSONLKeys) Override(field, key string) error {
+	switch strings.ToLower(field) {
+	case "host":
+		o.Host = key
+	case "user":
+		o.User = key
+	case "timestamp":
+		o.Timestamp = key
+	case "method":
+		o.Method = key
+	case "path":
+		o.Path = key
+	case "protocol":
+		o.Protocol = key
+	case "status":
+		o.Status = key
+	case "bytes":
+		o.Bytes = key
+	case "latency":
+		o.Latency = key
+	default:
+		return fmt.Errorf("unknown jsonl field %q", field)
+	}
+	return nil
+}
+
+// JSONL parses JSON Lines formatted strings, one JSON object
+// per line, representing http calls. It returns a well formed Trace.
+//
+// Only the timestamp is mandatory, missing fields are left empty.
+type JSONL struct {
+	keys JSONLKeys
+}
+
+// NewJSONL creates a new JSON Lines parser reading fields from keys
+func NewJSONL(keys JSONLKeys) *JSONL {
+	return &JSONL{keys: keys}
+}
+
+// Parse creates a Trace representing an HTTP call
+func (o *JSONL) Parse(raw string) (trace.Trace, error) {
+	d := json.NewDecoder(strings.NewReader(raw))
+	d.UseNumber()
+
+	obj := make(map[string]interface{})
+	if err := d.Decode(&obj); err != nil {
+		return trace.Trace{}, fmt.Errorf("jsonl parse error - %w", err)
+	}
+	// A line holds a single object, anything after it is garbage
+	if err := d.Decode(&json.RawMessage{}); err != io.EOF {
+		return trace.Trace{}, fmt.Errorf("jsonl parse error - unexpected data after the json object")
+	}
+
+	t := trace.Trace{}
+	ts, ok := lookup(obj, o.keys.Timestamp)
+	if !ok {
+		return trace.Trace{}, fmt.Errorf("jsonl parse error - missing required field %s", o.keys.Timestamp)
+	}
+	if err := parseJSONDate(&t, ts); err != nil {
+		return trace.Trace{}, err
+	}
+
+	if v, ok := lookupString(obj, o.keys.Host); ok {
+		if err := parseRemoteHost(&t, v); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+	if v, ok := lookupString(obj, o.keys.User); ok {
+		if err := parseAuthUser(&t, v); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+	if v, ok := lookupString(obj, o.keys.Method); ok {
+		if err := parseMethod(&t, v); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+	if v, ok := lookupString(obj, o.keys.Path); ok {
+		if err := parseSection(&t, v); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+	if v, ok := lookupString(obj, o.keys.Protocol); ok {
+		// Both "HTTP/1.1" and "1.1" are accepted
+		if !strings.Contains(v, "/") {
+			v = "HTTP/" + v
+		}
+		if err := parseVersion(&t, v); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+	if v, ok := lookupString(obj, o.keys.Status); ok {
+		if err := parseStatus(&t, v); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+	if v, ok := lookupString(obj, o.keys.Bytes); ok && v != "-" {
+		if err := parseBytes(&t, v); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+	if v, ok := lookupString(obj, o.keys.Latency); ok && v != "-" {
+		if err := parseLatency(&t, v); err != nil {
+			return trace.Trace{}, err
+		}
+	}
+
+	return t, nil
+}
+
+// lookup returns the value stored at key. If key is not found as is
+// it is split on dots to walk nested objects.
+func lookup(obj map[string]interface{}, key string) (interface{}, bool) {
+	if key == "" {
+		return nil, false
+	}
+	if v, ok := obj[key]; ok {
+		return v, v != nil
+	}
+
+	path := strings.Split(key, ".")
+	var cur interface{} = obj
+	for _, k := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+
+	return cur, cur != nil
+}
+
+// lookupString returns the value stored at key as a string.
+// Empty strings are considered missing.
+func lookupString(obj map[string]interface{}, key string) (string, bool) {
+	v, ok := lookup(obj, key)
+	if !ok {
+		return "", false
+	}
+
+	var s string
+	switch value := v.(type) {
+	case string:
+		s = value
+	case json.Number:
+		s = value.String()
+	case bool:
+		s = fmt.Sprint(value)
+	default:
+		return "", false
+	}
+
+	return s, s != ""
+}
+
+// parseJSONDate accepts epoch timestamps in seconds, possibly fractional,
+// either as a number or as a string, and RFC3339 strings.
+func parseJSONDate(t *trace.Trace, v interface{}) error {
+	if t == nil {
+		return fmt.Errorf("nil receiver")
+	}
+
+	var field string
+	switch value := v.(type) {
+	case json.Number:
+		field = value.String()
+	case string:
+		field = value
+	default:
+		return fmt.Errorf("jsonl parse error - unsupported timestamp type %T", v)
+	}
+
+	if date, err := time.Parse(time.RFC3339Nano, field); err == nil {
+		t.Date = date
+		return nil
+	}
+
+	return parseEpoch(t, field)
+}
+
+// parseEpoch parses a Unix timestamp in seconds, decimals being
+// fractions of a second
+func parseEpoch(t *trace.Trace, field string) error {
+	if t == nil {
+		return fmt.Errorf("nil receiver")
+	}
+
+	if unix, err := strconv.ParseInt(field, 10, 64); err == nil {
+		t.Date = time.Unix(unix, 0)
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", field)
+	}
+	t.Date = time.Unix(0, int64(f*float64(time.Second)))
+	return nil
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLParsesEpochTimestamp(t *testing.T) {
+	p := NewJSONL(DefaultJSONLKeys())
+	raw := `{"host":"10.0.0.2","user":"apache","timestamp":1549573860,"method":"get","path":"/api/user","protocol":"HTTP/1.0","status":200,"bytes":1234}`
+
+	tr, err := p.Parse(raw)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1549573860, 0), tr.Date)
+	assert.Equal(t, "10.0.0.2", tr.RemoteHost)
+	assert.Equal(t, "apache", tr.AuthUser)
+	assert.Equal(t, "GET", tr.Method)
+	assert.Equal(t, "/api", tr.Section)
+	assert.Equal(t, "1.0", tr.Version)
+	assert.Equal(t, uint(200), tr.Status)
+	assert.Equal(t, uint(1234), tr.Bytes)
+}
+
+func TestJSONLParsesRFC3339TimestampAndCustomKeys(t *testing.T) {
+	keys := DefaultJSONLKeys()
+	assert.NoError(t, keys.Override("timestamp", "ts"))
+	assert.NoError(t, keys.Override("status", "http.status"))
+	p := NewJSONL(keys)
+	raw := `{"ts":"2019-02-07T21:11:00Z","http":{"status":"503"}}`
+
+	tr, err := p.Parse(raw)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 2, 7, 21, 11, 0, 0, time.UTC), tr.Date)
+	assert.Equal(t, uint(503), tr.Status)
+}
+
+func TestJSONLToleratesMissingOptionalFields(t *testing.T) {
+	p := NewJSONL(DefaultJSONLKeys())
+
+	tr, err := p.Parse(`{"timestamp":"1549573860.5","path":"/help"}`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1549573860, int64(500*time.Millisecond)), tr.Date)
+	assert.Equal(t, "/help", tr.Section)
+	assert.Equal(t, "", tr.RemoteHost)
+	assert.Equal(t, uint(0), tr.Status)
+}
+
+func TestJSONLFailsWithoutTimestamp(t *testing.T) {
+	p := NewJSONL(DefaultJSONLKeys())
+
+	_, err := p.Parse(`{"path":"/help","status":200}`)
+
+	assert.Error(t, err)
+}
+
+func TestJSONLFailsWithTrailingData(t *testing.T) {
+	p := NewJSONL(DefaultJSONLKeys())
+
+	for _, line := range []string{
+		`{"timestamp":1549573860,"path":"/help","status":200} garbage`,
+		`{"timestamp":1549573860,"path":"/help","status":200} {"a":1}`,
+		`{"timestamp":1549573860,"path":"/help","status":200}}`,
+	} {
+		_, err := p.Parse(line)
+		assert.Error(t, err, line)
+	}
+
+	_, err := p.Parse(`{"timestamp":1549573860,"path":"/help","status":200}  `)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Options gathers the settings a parser may need when it is created.
+// Parsers ignore the options they do not use.
+type Options struct {
+	// Format is the log format template of template parsers
+	Format string
+	// JSONLKeys are the keys read by the jsonl parser
+	JSONLKeys JSONLKeys
+	// CSVDelimiter separates the fields of the csv parser
+	CSVDelimiter rune
+	// CSVHeader lists the csv columns when the stream has no header
+	CSVHeader []string
+}
+
+// DefaultOptions returns options with every parser's defaults
+func DefaultOptions() Options {
+	return Options{
+		JSONLKeys:    DefaultJSONLKeys(),
+		CSVDelimiter: ',',
+	}
+}
+
+// Factory creates a new parser instance
+type Factory func(Options) (Parser, error)
+
+// registry lists registered parsers in registration order.
+// This order is used to break ties when detecting a format.
+type registry struct {
+	mutex     sync.Mutex
+	names     []string
+	factories map[string]Factory
+}
+
+var gRegistry = registry{
+	factories: make(map[string]Factory),
+}
+
+// Built-in parsers, fixed formats come first so that they are preferred
+// to equivalent templates when detecting a format
+func init() {
+	Register("csv", func(o Options) (Parser, error) { return NewCustomCSV(o.CSVDelimiter, o.CSVHeader) })
+	Register("tsv", func(o Options) (Parser, error) { return NewCustomCSV('\t', o.CSVHeader) })
+	Register("jsonl", func(o Options) (Parser, error) { return NewJSONL(o.JSONLKeys), nil })
+	Register("clf", func(Options) (Parser, error) { return NewCLF(), nil })
+	Register("combined", func(Options) (Parser, error) { return NewCombined(), nil })
+	Register("nginx", func(o Options) (Parser, error) { return NewNginx(o.Format) })
+	Register("apache", func(o Options) (Parser, error) { return NewApache(o.Format) })
+}
+
+// Register makes a parser available under name.
+// It panics if name is empty or already registered.
+func Register(name string, f Factory) {
+	gRegistry.mutex.Lock()
+	defer gRegistry.mutex.Unlock()
+
+	name = strings.ToLower(name)
+	if name == "" || f == nil {
+		panic(fmt.Errorf("parser.Register: empty name or nil factory"))
+	}
+	if _, ok := gRegistry.factories[name]; ok {
+		panic(fmt.Errorf("parser.Register: %s is already registered", name))
+	}
+
+	gRegistry.names = append(gRegistry.names, name)
+	gRegistry.factories[name] = f
+}
+
+// New creates a new instance of the parser registered under name
+func New(name string, opts Options) (Parser, error) {
+	gRegistry.mutex.Lock()
+	f, ok := gRegistry.factories[strings.ToLower(name)]
+	gRegistry.mutex.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("parser %q is not registered, available: %s", name, strings.Join(Names(), ", "))
+	}
+
+	return f(opts)
+}
+
+// Names returns registered parser names in registration order
+func Names() []string {
+	gRegistry.mutex.Lock()
+	defer gRegistry.mutex.Unlock()
+
+	names := make([]string, len(gRegistry.names))
+	copy(names, gRegistry.names)
+	return names
+}
+
+// Score is the result of a parser's detection run
+type Score struct {
+	Name string
+	// Ratio of sample lines successfully parsed, headers included
+	Ratio float64
+}
+
+// Detect runs a new instance of every registered parser over the sample,
+// line by line, and returns the parser which parsed the most lines.
+// Ties go to the first registered parser.
+//
+// Parsers which cannot be created from opts do not compete.
+// An error is returned if no parser parsed any line.
+func Detect(sample []string, opts Options) (Score, error) {
+	best := Score{}
+	if len(sample) == 0 {
+		return best, fmt.Errorf("parser detection - empty sample")
+	}
+
+	for _, name := range Names() {
+		p, err := New(name, opts)
+		if err != nil {
+			continue
+		}
+
+		parsed := 0
+		for _, line := range sample {
+			if _, err := p.Parse(line); err == nil || err == ErrHeaderData {
+				parsed++
+			}
+		}
+
+		ratio := float64(parsed) / float64(len(sample))
+		if ratio > best.Ratio {
+			best = Score{Name: name, Ratio: ratio}
+		}
+	}
+
+	if best.Name == "" {
+		return best, fmt.Errorf("parser detection - no registered parser matches the input")
+	}
+
+	return best, nil
+}
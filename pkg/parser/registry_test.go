@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectPicksCSV(t *testing.T) {
+	sample := []string{
+		`"remotehost","rfc931","authuser","date","request","status","bytes"`,
+		`"10.0.0.2","-","apache",1549573860,"GET /api/user HTTP/1.0",200,1234`,
+		`"10.0.0.4","-","apache",1549573860,"GET /api/user HTTP/1.0",200,1234`,
+	}
+
+	score, err := Detect(sample, DefaultOptions())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "csv", score.Name)
+	assert.Equal(t, 1.0, score.Ratio)
+}
+
+func TestDetectPrefersCombinedOverEquivalentTemplate(t *testing.T) {
+	sample := []string{
+		`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 12 "-" "curl/8.0"`,
+		`10.0.0.1 - - [10/Oct/2000:13:55:37 -0700] "GET /api HTTP/1.1" 200 12 "-" "curl/8.0"`,
+		`garbage`,
+	}
+
+	score, err := Detect(sample, DefaultOptions())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "combined", score.Name)
+	assert.InDelta(t, 2.0/3.0, score.Ratio, 1e-9)
+}
+
+func TestDetectUsesConfiguredTemplate(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Format = `$time_iso8601 $status $request_uri`
+	sample := []string{
+		`2019-02-07T21:11:00Z 200 /api/user`,
+		`2019-02-07T21:11:01Z 404 /help`,
+	}
+
+	score, err := Detect(sample, opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx", score.Name)
+}
+
+func TestDetectFailsWhenNothingMatches(t *testing.T) {
+	_, err := Detect([]string{"foo", "bar"}, DefaultOptions())
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,444 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/trace"
+)
+
+// NginxCombined is nginx's predefined combined log_format
+const NginxCombined string = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`
+
+// ApacheCommon and ApacheCombined are the LogFormat strings conventionally
+// named common and combined in Apache configurations
+const (
+	ApacheCommon   string = `%h %l %u %t "%r" %>s %b`
+	ApacheCombined string = `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"`
+)
+
+// setter fills a trace field from a raw variable value
+type setter func(*trace.Trace, string) error
+
+// segment is a compiled piece of a log format template. It is either
+// literal text which must be found as is, or a variable.
+type segment struct {
+	literal string
+	name    string // variable name, empty for literals
+	set     setter // nil if the variable is not mapped onto a trace field
+}
+
+func (o segment) variable() bool {
+	return o.name != ""
+}
+
+// Template parses lines following a log format template
+// compiled from an nginx log_format or an Apache LogFormat string.
+//
+// The template is compiled once into a list of segments. Each variable
+// spans up to the literal text following it in the template, values
+// between double quotes may contain escaped quotes.
+type Template struct {
+	format   string
+	segments []segment
+}
+
+// NewNginx compiles an nginx log_format string such as
+// `$remote_addr - $remote_user [$time_local] "$request" $status`.
+// "combined" selects nginx's predefined format.
+func NewNginx(format string) (*Template, error) {
+	if format == "" || format == "combined" {
+		format = NginxCombined
+	}
+
+	segments, err := compileNginx(format)
+	if err != nil {
+		return nil, fmt.Errorf("nginx log_format - %w", err)
+	}
+
+	return newTemplate(format, segments)
+}
+
+// NewApache compiles an Apache LogFormat string such as
+// `%h %l %u %t \"%r\" %>s %b`. "common" and "combined" select
+// the conventional formats of the same name.
+func NewApache(format string) (*Template, error) {
+	switch format {
+	case "", "combined":
+		format = ApacheCombined
+	case "common":
+		format = ApacheCommon
+	}
+
+	segments, err := compileApache(format)
+	if err != nil {
+		return nil, fmt.Errorf("apache LogFormat - %w", err)
+	}
+
+	return newTemplate(format, segments)
+}
+
+func newTemplate(format string, segments []segment) (*Template, error) {
+	hasDate := false
+	for i, s := range segments {
+		if !s.variable() {
+			continue
+		}
+		if i > 0 && segments[i-1].variable() {
+			return nil, fmt.Errorf("variables %s and %s must be separated by literal text", segments[i-1].name, s.name)
+		}
+		if s.set != nil && isDateVariable(s.name) {
+			hasDate = true
+		}
+	}
+
+	if !hasDate {
+		return nil, fmt.Errorf("format %q has no time variable", format)
+	}
+
+	return &Template{
+		format:   format,
+		segments: segments,
+	}, nil
+}
+
+// Format returns the template's source format
+func (o *Template) Format() string {
+	return o.format
+}
+
+// Parse creates a Trace representing an HTTP call
+func (o *Template) Parse(raw string) (trace.Trace, error) {
+	t := trace.Trace{}
+
+	pos := 0
+	for i, s := range o.segments {
+		if !s.variable() {
+			if !strings.HasPrefix(raw[pos:], s.literal) {
+				return trace.Trace{}, fmt.Errorf("template parse error - expected %q at column %d", s.literal, pos)
+			}
+			pos += len(s.literal)
+			continue
+		}
+
+		end := len(raw)
+		if i+1 < len(o.segments) {
+			end = literalIndex(raw, pos, o.segments[i+1].literal)
+			if end < 0 {
+				return trace.Trace{}, fmt.Errorf("template parse error - %q not found after $%s", o.segments[i+1].literal, s.name)
+			}
+		}
+
+		if s.set != nil {
+			if err := s.set(&t, raw[pos:end]); err != nil {
+				return trace.Trace{}, fmt.Errorf("template parse error - %s: %w", s.name, err)
+			}
+		}
+		pos = end
+	}
+
+	return t, nil
+}
+
+// literalIndex returns the index of the first occurrence of literal in raw
+// from start. When literal starts with a double quote, escaped quotes are
+// skipped.
+func literalIndex(raw string, start int, literal string) int {
+	if !strings.HasPrefix(literal, `"`) {
+		i := strings.Index(raw[start:], literal)
+		if i < 0 {
+			return -1
+		}
+		return start + i
+	}
+
+	for i := start; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			if strings.HasPrefix(raw[i:], literal) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// compileNginx splits an nginx format into segments.
+// Variables are written $name or ${name}.
+func compileNginx(format string) ([]segment, error) {
+	segments := make([]segment, 0)
+	literal := strings.Builder{}
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '$' {
+			literal.WriteByte(format[i])
+			continue
+		}
+
+		var name string
+		if strings.HasPrefix(format[i+1:], "{") {
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '${' at column %d", i)
+			}
+			name = format[i+2 : i+end]
+			i += end
+		} else {
+			end := i + 1
+			for end < len(format) && isVariableChar(format[end]) {
+				end++
+			}
+			name = format[i+1 : end]
+			i = end - 1
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty variable name at column %d", i)
+		}
+
+		segments = appendLiteral(segments, &literal)
+		segments = append(segments, segment{
+			name: name,
+			set:  nginxSetter(name),
+		})
+	}
+
+	return appendLiteral(segments, &literal), nil
+}
+
+func isVariableChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// compileApache splits an Apache format into segments.
+// Directives are written %x, %>x, %<x, %{arg}x or %!200,304x,
+// %% is a literal percent sign.
+func compileApache(format string) ([]segment, error) {
+	segments := make([]segment, 0)
+	literal := strings.Builder{}
+
+	for i := 0; i < len(format); i++ {
+		switch format[i] {
+		case '\\':
+			// LogFormat strings are double quoted in Apache configurations
+			// so they usually contain escaped characters
+			if i+1 < len(format) {
+				i++
+				switch format[i] {
+				case 't':
+					literal.WriteByte('\t')
+				case 'n':
+					literal.WriteByte('\n')
+				default:
+					literal.WriteByte(format[i])
+				}
+				continue
+			}
+			literal.WriteByte(format[i])
+			continue
+		case '%':
+		default:
+			literal.WriteByte(format[i])
+			continue
+		}
+
+		if strings.HasPrefix(format[i+1:], "%") {
+			literal.WriteByte('%')
+			i++
+			continue
+		}
+
+		start := i
+		i++
+		// status code conditions and original/final request modifiers
+		for i < len(format) && strings.IndexByte("<>!,0123456789", format[i]) >= 0 {
+			i++
+		}
+		arg := ""
+		if i < len(format) && format[i] == '{' {
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '%%{' at column %d", start)
+			}
+			arg = format[i+1 : i+end]
+			i += end + 1
+		}
+		if i >= len(format) {
+			return nil, fmt.Errorf("truncated directive at column %d", start)
+		}
+
+		name := format[start : i+1]
+		set, err := apacheSetter(format[i], arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		segments = appendLiteral(segments, &literal)
+		segments = append(segments, segment{
+			name: name,
+			set:  set,
+		})
+	}
+
+	return appendLiteral(segments, &literal), nil
+}
+
+// appendLiteral appends the literal being built, if any, then resets it
+func appendLiteral(segments []segment, literal *strings.Builder) []segment {
+	if literal.Len() == 0 {
+		return segments
+	}
+	segments = append(segments, segment{literal: literal.String()})
+	literal.Reset()
+	return segments
+}
+
+// isDateVariable returns true if the variable sets the trace's date
+func isDateVariable(name string) bool {
+	switch name {
+	case "time_local", "time_iso8601", "msec":
+		return true
+	}
+	return strings.HasPrefix(name, "%") && strings.HasSuffix(name, "t")
+}
+
+// nginxSetter returns the setter associated with an nginx variable,
+// nil if the variable is not mapped onto a trace field
+func nginxSetter(name string) setter {
+	switch name {
+	case "remote_addr":
+		return parseRemoteHost
+	case "remote_user":
+		return optional(parseAuthUser)
+	case "time_local":
+		return parseCLFDate
+	case "time_iso8601":
+		return parseISO8601
+	case "msec":
+		return parseEpoch
+	case "request":
+		return parseRequest
+	case "request_method":
+		return parseMethod
+	case "request_uri", "uri":
+		return parseSection
+	case "server_protocol":
+		return parseVersion
+	case "status":
+		return parseStatus
+	case "body_bytes_sent", "bytes_sent":
+		return optional(parseBytes)
+	case "http_referer":
+		return parseReferer
+	case "http_user_agent":
+		return parseUserAgent
+	case "request_time":
+		return optional(parseLatency)
+	default:
+		return nil
+	}
+}
+
+// apacheSetter returns the setter associated with an Apache directive,
+// nil if the directive is not mapped onto a trace field
+func apacheSetter(directive byte, arg string) (setter, error) {
+	switch directive {
+	case 'h', 'a':
+		return parseRemoteHost, nil
+	case 'l':
+		return parseRFC931, nil
+	case 'u':
+		return optional(parseAuthUser), nil
+	case 't':
+		switch arg {
+		case "":
+			return parseBracketedCLFDate, nil
+		case "sec", "msec", "usec", "msec_frac", "usec_frac":
+			return epochSetter(arg), nil
+		default:
+			return nil, fmt.Errorf("strftime time formats are not supported")
+		}
+	case 'r':
+		return parseRequest, nil
+	case 'm':
+		return parseMethod, nil
+	case 'U':
+		return parseSection, nil
+	case 'H':
+		return parseVersion, nil
+	case 's':
+		return parseStatus, nil
+	case 'b', 'B', 'O':
+		return optional(parseBytes), nil
+	case 'D':
+		return latencySetter(time.Microsecond), nil
+	case 'T':
+		switch arg {
+		case "", "s":
+			return latencySetter(time.Second), nil
+		case "ms":
+			return latencySetter(time.Millisecond), nil
+		case "us":
+			return latencySetter(time.Microsecond), nil
+		default:
+			return nil, fmt.Errorf("unsupported time unit %s", arg)
+		}
+	case 'i':
+		switch strings.ToLower(arg) {
+		case "referer":
+			return parseReferer, nil
+		case "user-agent":
+			return parseUserAgent, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// optional skips "-" values, used by formats when a value is missing
+func optional(set setter) setter {
+	return func(t *trace.Trace, field string) error {
+		if field == "-" {
+			return nil
+		}
+		return set(t, field)
+	}
+}
+
+// epochSetter parses Apache's %{sec}t, %{msec}t and %{usec}t values
+func epochSetter(unit string) setter {
+	return func(t *trace.Trace, field string) error {
+		if t == nil {
+			return fmt.Errorf("nil receiver")
+		}
+		switch unit {
+		case "msec":
+			field = field[:max(len(field)-3, 0)] + "." + field[max(len(field)-3, 0):]
+		case "usec":
+			field = field[:max(len(field)-6, 0)] + "." + field[max(len(field)-6, 0):]
+		case "msec_frac", "usec_frac":
+			// Only a fraction of the current second, unusable on its own
+			return nil
+		}
+		return parseEpoch(t, field)
+	}
+}
+
+func parseBracketedCLFDate(t *trace.Trace, field string) error {
+	return parseCLFDate(t, strings.TrimSuffix(strings.TrimPrefix(field, "["), "]"))
+}
+
+func parseISO8601(t *trace.Trace, field string) error {
+	if t == nil {
+		return fmt.Errorf("nil receiver")
+	}
+
+	date, err := time.Parse(time.RFC3339, field)
+	if err != nil {
+		return err
+	}
+	t.Date = date
+	return nil
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNginxTemplateParsesCustomFormat(t *testing.T) {
+	p, err := NewNginx(`$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent $request_time`)
+	assert.NoError(t, err)
+	raw := `10.0.0.1 - bob [10/Oct/2000:13:55:36 -0700] "GET /api/user?id=1 HTTP/1.1" 404 512 0.042`
+
+	tr, err := p.Parse(raw)
+
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC).Equal(tr.Date))
+	assert.Equal(t, "10.0.0.1", tr.RemoteHost)
+	assert.Equal(t, "bob", tr.AuthUser)
+	assert.Equal(t, "/api", tr.Section)
+	assert.Equal(t, uint(404), tr.Status)
+	assert.Equal(t, uint(512), tr.Bytes)
+	assert.True(t, tr.HasLatency)
+	assert.Equal(t, 42*time.Millisecond, tr.Latency)
+}
+
+func TestNginxDefaultTemplateHandlesEscapedQuotes(t *testing.T) {
+	p, err := NewNginx("combined")
+	assert.NoError(t, err)
+	raw := `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /?q=1 HTTP/1.1" 200 - "-" "agent \"quoted\" v1"`
+
+	tr, err := p.Parse(raw)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/", tr.Section)
+	assert.Equal(t, `agent \"quoted\" v1`, tr.UserAgent)
+}
+
+func TestApacheTemplateParsesEscapedLogFormat(t *testing.T) {
+	p, err := NewApache(`%h %l %u %{sec}t \"%r\" %>s %b %D \"%{User-Agent}i\"`)
+	assert.NoError(t, err)
+	raw := `10.0.0.1 - - 1549573860 "DELETE /api/user HTTP/1.0" 500 - 1500 "curl/8.0"`
+
+	tr, err := p.Parse(raw)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1549573860, 0), tr.Date)
+	assert.Equal(t, "DELETE", tr.Method)
+	assert.Equal(t, uint(500), tr.Status)
+	assert.Equal(t, "curl/8.0", tr.UserAgent)
+	assert.Equal(t, 1500*time.Microsecond, tr.Latency)
+}
+
+func TestTemplateCompilationErrors(t *testing.T) {
+	_, noDate := NewNginx(`$remote_addr "$request"`)
+	_, adjacent := NewNginx(`$time_local$status`)
+	_, strftime := NewApache(`%{%Y-%m-%d}t %h`)
+
+	assert.Error(t, noDate)
+	assert.Error(t, adjacent)
+	assert.Error(t, strftime)
+}
@@ -1,8 +1,15 @@
 package reader
 
+import "time"
+
 // Reader is an interface used to read a string stream
 type Reader interface {
 	Open(source string) error
 	Read() (string, error)
+	// ReadTimeout is Read giving up after timeout, ok is false then
+	ReadTimeout(timeout time.Duration) (line string, ok bool, err error)
 	Close() error
+	// Buffered returns the number of lines read but not consumed yet
+	// and the buffer's capacity
+	Buffered() (int, int)
 }
@@ -63,12 +63,30 @@ func (o *Stdin) buffering() error {
 // Note: an empty string is considered as no entry by the Reader
 // (default behaviour of bufio.Scan when reading stdin)
 func (o *Stdin) Read() (line string, err error) {
+	o.start()
+	return <-o.lines, nil
+}
+
+func (o *Stdin) ReadTimeout(timeout time.Duration) (string, bool, error) {
+	o.start()
+	select {
+	case line := <-o.lines:
+		return line, true, nil
+	case <-time.After(timeout):
+		return "", false, nil
+	}
+}
+
+// start starts bufferize() on the first read
+func (o *Stdin) start() {
 	if !o.once {
 		go o.bufferize()
 		o.once = true
 	}
+}
 
-	return <-o.lines, nil
+func (o *Stdin) Buffered() (int, int) {
+	return len(o.lines), cap(o.lines)
 }
 
 func (o *Stdin) Close() error {
@@ -2,6 +2,7 @@ package reader
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/nxadm/tail"
 	"gopkg.in/tomb.v1"
@@ -75,6 +76,19 @@ func (o *Tail) Read() (string, error) {
 	return <-o.buffer, nil
 }
 
+func (o *Tail) ReadTimeout(timeout time.Duration) (string, bool, error) {
+	select {
+	case line := <-o.buffer:
+		return line, true, nil
+	case <-time.After(timeout):
+		return "", false, nil
+	}
+}
+
+func (o *Tail) Buffered() (int, int) {
+	return len(o.buffer), cap(o.buffer)
+}
+
 // Close removes inotify watches added by the tail package.
 // The linux kernel may not clean it at process exit.
 func (o *Tail) Close() error {
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/backend"
+)
+
+// alertHistory returns the alert state changes selected by the alert,
+// state (comma separated), from and to (RFC 3339) and limit parameters
+func (o *Server) alertHistory(w http.ResponseWriter, r *http.Request) {
+	q, err := historyQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, o.source.AlertHistory(q))
+}
+
+func historyQuery(params url.Values) (backend.HistoryQuery, error) {
+	q := backend.HistoryQuery{Alert: alert.NameT(params.Get("alert"))}
+
+	if states := params.Get("state"); states != "" {
+		for _, s := range strings.Split(states, ",") {
+			state, err := alert.ParseState(strings.TrimSpace(s))
+			if err != nil {
+				return q, fmt.Errorf("state - %s", err)
+			}
+			q.States = append(q.States, state)
+		}
+	}
+
+	var err error
+	if from := params.Get("from"); from != "" {
+		if q.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return q, fmt.Errorf("from - expecting an RFC 3339 date, received %q", from)
+		}
+	}
+	if to := params.Get("to"); to != "" {
+		if q.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return q, fmt.Errorf("to - expecting an RFC 3339 date, received %q", to)
+		}
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 0 {
+			return q, fmt.Errorf("limit - expecting a positive number, received %q", limit)
+		}
+	}
+
+	return q, nil
+}
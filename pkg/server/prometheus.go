@@ -0,0 +1,361 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/backend"
+	"github.com/julnicolas/httpmon/pkg/metrics"
+)
+
+// Prometheus metric types
+const (
+	counterT string = "counter"
+	gaugeT   string = "gauge"
+	summaryT string = "summary"
+)
+
+// Source is what the exporter reads metrics from, implemented by backend.Backend
+type Source interface {
+	Metric(name string) (metrics.Metric, error)
+	AlertStates() []backend.AlertStateTransition
+	AlertHistory(q backend.HistoryQuery) []backend.HistoryEntry
+	ParseErrors() uint64
+	Dropped() uint64
+	DroppedAlerts() uint64
+	Ready() bool
+	State() backend.State
+	Silences() []alert.Silence
+	AddSilence(s alert.Silence) alert.Silence
+	ExpireSilence(id string) error
+}
+
+// exposition writes metrics in the Prometheus text exposition format
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+type exposition struct {
+	w   io.Writer
+	err error // first write error, next writes are skipped
+}
+
+// family writes the HELP and TYPE lines of a metric family
+func (o *exposition) family(name, typ, help string) {
+	o.printf("# HELP %s %s\n", name, escapeHelp(help))
+	o.printf("# TYPE %s %s\n", name, typ)
+}
+
+// sample writes a sample, labels are name/value pairs
+func (o *exposition) sample(name string, value float64, labels ...string) {
+	if len(labels)%2 != 0 {
+		// Programming error, should never happen
+		panic(fmt.Errorf("odd number of label names and values for %s", name))
+	}
+
+	if len(labels) == 0 {
+		o.printf("%s %s\n", name, formatValue(value))
+		return
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], escapeLabel(labels[i+1])))
+	}
+	o.printf("%s{%s} %s\n", name, strings.Join(pairs, ","), formatValue(value))
+}
+
+func (o *exposition) printf(format string, a ...interface{}) {
+	if o.err != nil {
+		return
+	}
+	_, o.err = fmt.Fprintf(o.w, format, a...)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// WriteMetrics writes every metric of source in the Prometheus text format
+func WriteMetrics(w io.Writer, source Source) error {
+	e := &exposition{w: w}
+
+	if err := writeRequestsPerHost(e, source); err != nil {
+		return err
+	}
+	if err := writeRoutesPerStatus(e, source); err != nil {
+		return err
+	}
+	if err := writeRequestsPerSecond(e, source); err != nil {
+		return err
+	}
+	if err := writeLatency(e, source); err != nil {
+		return err
+	}
+	if err := writeBytes(e, source); err != nil {
+		return err
+	}
+	if err := writeStatusClasses(e, source); err != nil {
+		return err
+	}
+	writeIngestion(e, source)
+	writeAlerts(e, source.AlertStates())
+
+	return e.err
+}
+
+func writeRequestsPerHost(e *exposition, source Source) error {
+	m, err := source.Metric(metrics.ReqsPerHost)
+	if err != nil {
+		return err
+	}
+	c, ok := m.(metrics.Counter)
+	if !ok {
+		return fmt.Errorf("interface cast error - expected Counter")
+	}
+
+	e.family("httpmon_requests_total", counterT, "Number of http requests read from the stream.")
+	e.sample("httpmon_requests_total", c.Total())
+
+	e.family("httpmon_host_requests_total", counterT, "Number of http requests per remote host.")
+	labels := c.TypedLabels()
+	for _, host := range sortedKeys(labels) {
+		e.sample("httpmon_host_requests_total", labels[host], "host", host)
+	}
+
+	return nil
+}
+
+func writeRoutesPerStatus(e *exposition, source Source) error {
+	m, err := source.Metric(metrics.RoutesPerStatusN)
+	if err != nil {
+		return err
+	}
+	c, ok := m.(metrics.RoutePerStatusCounter)
+	if !ok {
+		return fmt.Errorf("interface cast error - expected RoutePerStatusCounter")
+	}
+
+	e.family("httpmon_section_requests_total", counterT, "Number of http requests per section and status code.")
+	perStatus := c.TypedLabels()
+	statuses := make([]metrics.StatusCodeT, 0, len(perStatus))
+	for status := range perStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i] < statuses[j] })
+
+	for _, status := range statuses {
+		sections := perStatus[status]
+		for _, section := range sortedKeys(sections) {
+			e.sample("httpmon_section_requests_total", sections[section],
+				"section", section, "status", fmt.Sprint(status))
+		}
+	}
+
+	return nil
+}
+
+// writeRequestsPerSecond exposes the average rate of the last closed period
+func writeRequestsPerSecond(e *exposition, source Source) error {
+	m, err := source.Metric(metrics.ReqsPerS)
+	if err != nil {
+		return err
+	}
+	c, ok := m.(metrics.CounterVector)
+	if !ok {
+		return fmt.Errorf("interface cast error - expected CounterVector")
+	}
+
+	e.family("httpmon_requests_per_second", gaugeT, "Average requests per second over the last closed --period.")
+	if v, ok := last(c.Total()); ok {
+		e.sample("httpmon_requests_per_second", v)
+	}
+
+	e.family("httpmon_section_requests_per_second", gaugeT, "Average requests per second per section over the last closed --period.")
+	labels := c.TypedLabels()
+	for _, section := range sortedKeys(labels) {
+		if v, ok := last(labels[section]); ok {
+			e.sample("httpmon_section_requests_per_second", v, "section", section)
+		}
+	}
+
+	return nil
+}
+
+// writeStatusClasses exposes the average rate per status class of the last closed period
+func writeStatusClasses(e *exposition, source Source) error {
+	m, err := source.Metric(metrics.StatusClassesN)
+	if err != nil {
+		return err
+	}
+	c, ok := m.(metrics.ClassVector)
+	if !ok {
+		return fmt.Errorf("interface cast error - expected ClassVector")
+	}
+
+	e.family("httpmon_status_class_requests_per_second", gaugeT, "Average requests per second per status class over the last closed --period.")
+	total := c.Total()
+	for _, class := range sortedKeys(total) {
+		if v, ok := last(total[class]); ok {
+			e.sample("httpmon_status_class_requests_per_second", v, "class", class)
+		}
+	}
+
+	e.family("httpmon_section_status_class_requests_per_second", gaugeT, "Average requests per second per section and status class over the last closed --period.")
+	labels := c.TypedLabels()
+	for _, section := range sortedKeys(labels) {
+		classes := labels[section]
+		for _, class := range sortedKeys(classes) {
+			if v, ok := last(classes[class]); ok {
+				e.sample("httpmon_section_status_class_requests_per_second", v, "section", section, "class", class)
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeLatency exposes latency quantiles of the last closed period
+func writeLatency(e *exposition, source Source) error {
+	m, err := source.Metric(metrics.LatencyN)
+	if err != nil {
+		return err
+	}
+	q, ok := m.(metrics.QuantileVector)
+	if !ok {
+		return fmt.Errorf("interface cast error - expected QuantileVector")
+	}
+
+	e.family("httpmon_request_latency_seconds", gaugeT, "Request latency quantiles over the last closed --period.")
+	for i, quantile := range q.Quantiles() {
+		if v, ok := last(q.Total()[i]); ok {
+			e.sample("httpmon_request_latency_seconds", v, "quantile", formatValue(quantile))
+		}
+	}
+
+	e.family("httpmon_section_request_latency_seconds", gaugeT, "Request latency quantiles per section over the last closed --period.")
+	labels := q.TypedLabels()
+	for _, section := range sortedKeys(labels) {
+		for i, quantile := range q.Quantiles() {
+			if v, ok := last(labels[section][i]); ok {
+				e.sample("httpmon_section_request_latency_seconds", v,
+					"section", section, "quantile", formatValue(quantile))
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeBytes exposes the response size distribution as summaries, quantiles
+// over the retained periods like a sliding window, count and sum since start
+func writeBytes(e *exposition, source Source) error {
+	m, err := source.Metric(metrics.BytesN)
+	if err != nil {
+		return err
+	}
+	d, ok := m.(metrics.Distribution)
+	if !ok {
+		return fmt.Errorf("interface cast error - expected Distribution")
+	}
+
+	e.family("httpmon_response_size_bytes", summaryT, "Response size distribution over the retained periods, count and sum since start.")
+	writeSummary(e, "httpmon_response_size_bytes", d.Quantiles(), d.Merge(0), d.Observed())
+
+	e.family("httpmon_section_response_size_bytes", summaryT, "Response size distribution per section over the retained periods, count and sum since start.")
+	labels := d.TypedLabels()
+	for _, section := range sortedKeys(labels) {
+		s, _ := d.LabelMerge(section, 0)
+		writeSummary(e, "httpmon_section_response_size_bytes", d.Quantiles(), s, d.LabelObserved(section), "section", section)
+	}
+
+	return nil
+}
+
+func writeSummary(e *exposition, name string, quantiles []float64, s *metrics.Sketch, observed metrics.Observed, labels ...string) {
+	if s.Count() > 0 {
+		for _, quantile := range quantiles {
+			l := append([]string{}, labels...)
+			e.sample(name, s.Quantile(quantile), append(l, "quantile", formatValue(quantile))...)
+		}
+	}
+	e.sample(name+"_sum", observed.Sum, labels...)
+	e.sample(name+"_count", float64(observed.Count), labels...)
+}
+
+func writeIngestion(e *exposition, source Source) {
+	e.family("httpmon_parse_errors_total", counterT, "Number of log lines which could not be parsed.")
+	e.sample("httpmon_parse_errors_total", float64(source.ParseErrors()))
+
+	e.family("httpmon_late_traces_dropped_total", counterT, "Number of traces dropped because they were older than --lateness.")
+	e.sample("httpmon_late_traces_dropped_total", float64(source.Dropped()))
+
+	e.family("httpmon_alert_transitions_dropped_total", counterT, "Number of alert state transitions dropped because the UI did not read them fast enough.")
+	e.sample("httpmon_alert_transitions_dropped_total", float64(source.DroppedAlerts()))
+}
+
+// writeAlerts exposes pending and active alerts like Prometheus'
+// ALERTS series, inactive alerts have no sample
+func writeAlerts(e *exposition, states []backend.AlertStateTransition) {
+	e.family("ALERTS", gaugeT, "Pending and firing alerts.")
+	for _, s := range states {
+		var state string
+		switch s.Alert.State() {
+		case alert.Pending:
+			state = "pending"
+		case alert.Active:
+			state = "firing"
+		default:
+			continue
+		}
+		e.sample("ALERTS", 1, append(alertLabels(s.Alert.Labels()), "alertstate", state)...)
+	}
+}
+
+// alertLabels returns an alert's labels as name/value pairs,
+// alertname first then sorted by name
+func alertLabels(l alert.Labels) []string {
+	pairs := []string{alert.AlertName, l[alert.AlertName]}
+	for _, name := range sortedKeys(l) {
+		if name != alert.AlertName {
+			pairs = append(pairs, name, l[name])
+		}
+	}
+	return pairs
+}
+
+// last returns the last value of a series, false if it is empty
+func last(series []float64) (float64, bool) {
+	if len(series) == 0 {
+		return 0, false
+	}
+	return series[len(series)-1], true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
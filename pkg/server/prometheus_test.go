@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/backend"
+	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/julnicolas/httpmon/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSource serves metrics from probers fed by the test
+type fakeSource struct {
+	probers  map[string]metrics.Prober
+	alerts   []backend.AlertStateTransition
+	history  []backend.HistoryEntry
+	query    backend.HistoryQuery // last history query
+	silences []alert.Silence
+	ready    bool
+}
+
+func newFakeSource(traces ...trace.Trace) *fakeSource {
+	o := &fakeSource{probers: make(map[string]metrics.Prober)}
+	for _, p := range []metrics.Prober{
+		metrics.NewRequestsPerHost(),
+		metrics.NewRoutePerStatus(),
+		metrics.NewRequestsPerSecond(10*time.Second, 10),
+		metrics.NewLatency(10*time.Second, 10),
+		metrics.NewBytes(10*time.Second, 10),
+		metrics.NewStatusClasses(10*time.Second, 10),
+	} {
+		for _, t := range traces {
+			p.Update(t)
+		}
+		o.probers[p.Metric().Name()] = p
+	}
+	return o
+}
+
+func (o *fakeSource) Metric(name string) (metrics.Metric, error) {
+	p, ok := o.probers[name]
+	if !ok {
+		return nil, fmt.Errorf("metric %s not found", name)
+	}
+	return p.DeepCopy(), nil
+}
+
+func (o *fakeSource) AlertStates() []backend.AlertStateTransition { return o.alerts }
+func (o *fakeSource) AlertHistory(q backend.HistoryQuery) []backend.HistoryEntry {
+	o.query = q
+	return o.history
+}
+
+func (o *fakeSource) ParseErrors() uint64       { return 3 }
+func (o *fakeSource) Dropped() uint64           { return 1 }
+func (o *fakeSource) DroppedAlerts() uint64     { return 2 }
+func (o *fakeSource) Ready() bool               { return o.ready }
+func (o *fakeSource) State() backend.State      { return backend.State{Parser: "csv"} }
+func (o *fakeSource) Silences() []alert.Silence { return o.silences }
+
+func (o *fakeSource) AddSilence(s alert.Silence) alert.Silence {
+	s.ID = fmt.Sprint(len(o.silences))
+	o.silences = append(o.silences, s)
+	return s
+}
+
+func (o *fakeSource) ExpireSilence(id string) error {
+	for i, s := range o.silences {
+		if s.ID == id {
+			o.silences = append(o.silences[:i], o.silences[i+1:]...)
+			return nil
+		}
+	}
+	return backend.ErrSilenceNotFound
+}
+
+func req(unix int64, host, section string, status uint) trace.Trace {
+	return trace.Trace{
+		Date:       time.Unix(unix, 0),
+		RemoteHost: host,
+		Section:    section,
+		Status:     status,
+		Bytes:      100,
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	source := newFakeSource(
+		req(100, "10.0.0.1", "/api", 200),
+		req(101, "10.0.0.2", "/api", 500),
+		req(105, "10.0.0.1", `/"quoted"`, 200),
+		req(110, "10.0.0.1", "/api", 200), // closes the first period
+	)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, WriteMetrics(buf, source))
+	out := buf.String()
+
+	assert.Contains(t, out, "# TYPE httpmon_requests_total counter\nhttpmon_requests_total 4\n")
+	assert.Contains(t, out, `httpmon_host_requests_total{host="10.0.0.1"} 3`+"\n")
+	assert.Contains(t, out, `httpmon_section_requests_total{section="/api",status="500"} 1`+"\n")
+	assert.Contains(t, out, `httpmon_section_requests_total{section="/\"quoted\"",status="200"} 1`+"\n")
+	assert.Contains(t, out, "httpmon_requests_per_second 0.3\n")
+	assert.Contains(t, out, `httpmon_section_status_class_requests_per_second{section="/api",class="5xx"} 0.1`+"\n")
+	assert.Contains(t, out, `httpmon_section_requests_per_second{section="/api"} 0.2`+"\n")
+	// Quantiles over the closed period, count and sum since start
+	assert.Contains(t, out, `httpmon_response_size_bytes{quantile="0.5"} 100`+"\n")
+	assert.Contains(t, out, "httpmon_response_size_bytes_count 4\n")
+	assert.Contains(t, out, "httpmon_response_size_bytes_sum 400\n")
+	assert.Contains(t, out, "httpmon_parse_errors_total 3\n")
+	assert.Contains(t, out, "httpmon_late_traces_dropped_total 1\n")
+	assert.Contains(t, out, "httpmon_alert_transitions_dropped_total 2\n")
+	assert.NotContains(t, out, "ALERTS{")
+}
+
+func TestWriteMetricsAlerts(t *testing.T) {
+	source := newFakeSource(req(100, "10.0.0.1", "/", 200), req(110, "10.0.0.1", "/", 200))
+	a := alert.NewRequestsPerSecond(alert.RequestsPerSecondInput{Period: time.Minute, Threshold: 0})
+	m, _ := source.Metric(metrics.ReqsPerS)
+	a.Eval(m)
+	source.alerts = []backend.AlertStateTransition{{Alert: a}}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, WriteMetrics(buf, source))
+
+	assert.Contains(t, buf.String(), fmt.Sprintf(`ALERTS{alertname="%s",alertstate="pending"} 1`+"\n", alert.ReqPerS))
+}
+
+func TestWriteMetricsFannedOutAlerts(t *testing.T) {
+	source := newFakeSource(
+		req(100, "10.0.0.1", "/api", 500),
+		req(101, "10.0.0.1", "/help", 200),
+	)
+	rule := alert.NewFanOut(alert.RuleInput{
+		Name:        "API errors",
+		Metric:      metrics.RoutesPerStatusN,
+		Selector:    map[string]string{"status": "500"},
+		By:          "section",
+		Aggregation: alert.Last,
+		Operator:    alert.GreaterOrEqual,
+		Threshold:   1,
+	})
+	m, _ := source.Metric(metrics.RoutesPerStatusN)
+	for _, a := range rule.Eval(m) {
+		source.alerts = append(source.alerts, backend.AlertStateTransition{Alert: a})
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, WriteMetrics(buf, source))
+
+	assert.Contains(t, buf.String(), `ALERTS{alertname="API errors",section="/api",status="500",alertstate="pending"} 1`+"\n")
+	assert.NotContains(t, buf.String(), `section=\"`)
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// contentType is the Prometheus text exposition format's content type
+const contentType string = "text/plain; version=0.0.4; charset=utf-8"
+
+// Reloader reloads the configuration, see config.Reload
+type Reloader func() (config.Changes, error)
+
+// Server exposes the backend's metrics and state over http
+type Server struct {
+	source Source
+	mutex  sync.Mutex
+	conf   config.Config // effective configuration
+	reload Reloader      // nil if reloads are not supported
+	http   *http.Server
+	addr   string // listening address, set once started
+}
+
+// NewServer creates a server listening on conf.Server.Listen,
+// "host:port" or ":port"
+func NewServer(conf config.Config, source Source) *Server {
+	o := &Server{source: source, conf: conf}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", get(o.metrics))
+	mux.HandleFunc("/health", get(o.health))
+	mux.HandleFunc("/ready", get(o.ready))
+	mux.HandleFunc("/config", get(o.config))
+	mux.HandleFunc("/api/alerts/history", get(o.alertHistory))
+	// Endpoints changing the app's state are not authenticated
+	if conf.Server.Admin {
+		mux.HandleFunc("/-/reload", o.reloadConfig)
+		mux.HandleFunc("/api/silences", o.silences)
+		mux.HandleFunc("/api/silences/", o.expireSilence)
+	} else {
+		mux.HandleFunc("/api/silences", get(o.silences))
+	}
+	if conf.Server.Debug {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		mux.HandleFunc("/debug/state", get(o.state))
+	}
+	o.http = &http.Server{
+		Addr:              conf.Server.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return o
+}
+
+// Start listens then serves requests in another goroutine.
+// An error is returned if the address cannot be listened on.
+func (o *Server) Start() error {
+	l, err := net.Listen("tcp", o.http.Addr)
+	if err != nil {
+		return err
+	}
+	o.addr = l.Addr().String()
+
+	go func() {
+		if err := o.http.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Errorf("http server - %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns the address the server listens on, empty until started
+func (o *Server) Addr() string {
+	return o.addr
+}
+
+// Close stops the server, waiting a few seconds for ongoing requests
+func (o *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return o.http.Shutdown(ctx)
+}
+
+// SetConfig updates the effective configuration, after a reload
+func (o *Server) SetConfig(conf config.Config) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.conf = conf
+}
+
+// OnReload sets the function reloading the configuration on POST /-/reload
+func (o *Server) OnReload(reload Reloader) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.reload = reload
+}
+
+// get only lets GET and HEAD requests through to h
+func get(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// health returns 200 as long as the process is alive
+func (o *Server) health(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// ready returns 200 once the input stream is opened and
+// a first trace has been parsed, 503 before
+func (o *Server) ready(w http.ResponseWriter, r *http.Request) {
+	if !o.source.Ready() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// config returns the effective configuration as JSON
+func (o *Server) config(w http.ResponseWriter, r *http.Request) {
+	o.mutex.Lock()
+	conf := o.conf
+	o.mutex.Unlock()
+
+	writeJSON(w, conf)
+}
+
+// reloadConfig reloads the configuration then returns the changed keys
+func (o *Server) reloadConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	o.mutex.Lock()
+	reload := o.reload
+	o.mutex.Unlock()
+	if reload == nil {
+		http.Error(w, "reload is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	changes, err := reload()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, changes)
+}
+
+// state returns a snapshot of the backend internals as JSON
+func (o *Server) state(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, o.source.State())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+}
+
+func (o *Server) metrics(w http.ResponseWriter, r *http.Request) {
+	// Buffered so that an error can still be reported with a status code
+	buf := &bytes.Buffer{}
+	if err := WriteMetrics(buf, o.source); err != nil {
+		logrus.Errorf("/metrics - %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
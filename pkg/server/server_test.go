@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/backend"
+	"github.com/julnicolas/httpmon/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(o *Server, method, path string) *httptest.ResponseRecorder {
+	return serveBody(o, method, path, "")
+}
+
+func serveBody(o *Server, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	o.http.Handler.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestServerReadiness(t *testing.T) {
+	source := newFakeSource()
+	o := NewServer(config.Default(), source)
+
+	assert.Equal(t, http.StatusOK, serve(o, http.MethodGet, "/health").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(o, http.MethodGet, "/ready").Code)
+
+	source.ready = true
+	assert.Equal(t, http.StatusOK, serve(o, http.MethodGet, "/ready").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(o, http.MethodPost, "/health").Code)
+}
+
+func TestServerConfig(t *testing.T) {
+	conf := config.Default()
+	conf.File = "access.log"
+	o := NewServer(conf, newFakeSource())
+
+	w := serve(o, http.MethodGet, "/config")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	got := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "access.log", got["file"])
+	assert.Equal(t, "10s", got["period"])
+	assert.Equal(t, "1m0s", got["alert"].(map[string]interface{})["requests_per_second"].(map[string]interface{})["period"])
+}
+
+func TestServerDebugEndpointsAreOptIn(t *testing.T) {
+	conf := config.Default()
+	o := NewServer(conf, newFakeSource())
+	assert.Equal(t, http.StatusNotFound, serve(o, http.MethodGet, "/debug/state").Code)
+	assert.Equal(t, http.StatusNotFound, serve(o, http.MethodGet, "/debug/pprof/").Code)
+
+	conf.Server.Debug = true
+	o = NewServer(conf, newFakeSource())
+	w := serve(o, http.MethodGet, "/debug/state")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"parser": "csv"`)
+	assert.Equal(t, http.StatusOK, serve(o, http.MethodGet, "/debug/pprof/").Code)
+}
+
+func TestServerAdminEndpointsAreOptIn(t *testing.T) {
+	source := newFakeSource()
+	o := NewServer(config.Default(), source)
+	o.OnReload(func() (config.Changes, error) { return config.Changes{}, nil })
+
+	assert.Equal(t, http.StatusNotFound, serve(o, http.MethodPost, "/-/reload").Code)
+	w := serveBody(o, http.MethodPost, "/api/silences", `{"matchers": {"alertname": "errors"}, "duration": "1h"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Empty(t, source.silences)
+	assert.Equal(t, http.StatusNotFound, serve(o, http.MethodDelete, "/api/silences/0").Code)
+	assert.Equal(t, http.StatusOK, serve(o, http.MethodGet, "/api/silences").Code)
+}
+
+func TestServerReload(t *testing.T) {
+	conf := config.Default()
+	conf.Server.Admin = true
+	o := NewServer(conf, newFakeSource())
+	assert.Equal(t, http.StatusNotImplemented, serve(o, http.MethodPost, "/-/reload").Code)
+
+	o.OnReload(func() (config.Changes, error) {
+		return config.Changes{Applied: []string{"alert"}, Restart: []string{"period"}}, nil
+	})
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(o, http.MethodGet, "/-/reload").Code)
+
+	w := serve(o, http.MethodPost, "/-/reload")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"applied": ["alert"], "restart_required": ["period"]}`, w.Body.String())
+}
+
+func TestServerSilences(t *testing.T) {
+	conf := config.Default()
+	conf.Server.Admin = true
+	source := newFakeSource()
+	o := NewServer(conf, source)
+
+	w := serveBody(o, http.MethodPost, "/api/silences", `{"matchers": {"alertname": "errors"}, "duration": "1h", "comment": "load test"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Len(t, source.silences, 1)
+	s := source.silences[0]
+	assert.Equal(t, time.Hour, s.EndsAt.Sub(s.StartsAt))
+	assert.Equal(t, alert.CreatedByAPI, s.CreatedBy)
+
+	w = serve(o, http.MethodGet, "/api/silences")
+	var silences []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &silences))
+	assert.Equal(t, map[string]interface{}{"alertname": "errors"}, silences[0]["matchers"])
+	assert.Equal(t, "load test", silences[0]["comment"])
+
+	assert.Equal(t, http.StatusBadRequest, serveBody(o, http.MethodPost, "/api/silences", `{"matchers": {}, "duration": "1h"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveBody(o, http.MethodPost, "/api/silences", `{"matchers": {"alertname": "a"}}`).Code)
+
+	assert.Equal(t, http.StatusNoContent, serve(o, http.MethodDelete, "/api/silences/"+s.ID).Code)
+	assert.Equal(t, http.StatusNotFound, serve(o, http.MethodDelete, "/api/silences/"+s.ID).Code)
+}
+
+func TestServerAlertHistory(t *testing.T) {
+	source := newFakeSource()
+	source.history = []backend.HistoryEntry{{Time: time.Unix(100, 0).UTC(), Alert: "busy", State: alert.Active, Prev: alert.Pending}}
+	o := NewServer(config.Default(), source)
+
+	w := serve(o, http.MethodGet, "/api/alerts/history?alert=busy&state=active,inactive&from=1970-01-01T00:00:00Z&limit=10")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, backend.HistoryQuery{
+		Alert:  "busy",
+		States: []alert.State{alert.Active, alert.Inactive},
+		From:   time.Unix(0, 0).UTC(),
+		Limit:  10,
+	}, source.query)
+
+	var entries []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Equal(t, "Active", entries[0]["state"])
+
+	assert.Equal(t, http.StatusBadRequest, serve(o, http.MethodGet, "/api/alerts/history?state=firing").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(o, http.MethodGet, "/api/alerts/history?to=yesterday").Code)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/backend"
+)
+
+// silenceRequest is the body of POST /api/silences,
+// the silence ends at EndsAt or after Duration
+type silenceRequest struct {
+	Matchers map[string]string `json:"matchers"`
+	StartsAt time.Time         `json:"starts_at"` // now if empty
+	EndsAt   time.Time         `json:"ends_at"`
+	Duration string            `json:"duration"` // go duration format
+	Comment  string            `json:"comment"`
+}
+
+// silence returns the requested silence
+func (o silenceRequest) silence(now time.Time) (alert.Silence, error) {
+	start := o.StartsAt
+	if start.IsZero() {
+		start = now
+	}
+
+	end := o.EndsAt
+	if o.Duration != "" {
+		if !end.IsZero() {
+			return alert.Silence{}, fmt.Errorf("expecting either ends_at or duration")
+		}
+		d, err := time.ParseDuration(o.Duration)
+		if err != nil {
+			return alert.Silence{}, fmt.Errorf("duration - %s", err)
+		}
+		end = start.Add(d)
+	}
+
+	return alert.NewSilence(o.Matchers, start, end, o.Comment, alert.CreatedByAPI)
+}
+
+// silences lists silences on GET and creates one on POST
+func (o *Server) silences(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeJSON(w, o.source.Silences())
+	case http.MethodPost:
+		var req silenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s, err := req.silence(time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Headers are written with the status
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, o.source.AddSilence(s))
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// expireSilence removes the silence of DELETE /api/silences/<id>
+func (o *Server) expireSilence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := o.source.ExpireSilence(strings.TrimPrefix(r.URL.Path, "/api/silences/"))
+	switch {
+	case errors.Is(err, backend.ErrSilenceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
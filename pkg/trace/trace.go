@@ -25,4 +25,15 @@ type Trace struct {
 	Status uint
 	// Bytes corresponds to the number of bytes sent
 	Bytes uint // bytes sent
+	// Latency is the time taken to serve the request,
+	// only relevant if HasLatency is true
+	Latency    time.Duration
+	HasLatency bool
+	// Referer is the page the request comes from, when logged
+	Referer string
+	// UserAgent identifies the client software, when logged
+	UserAgent string
+	// Attributes are extra values found in logs, by name
+	// e.g. columns a CSV parser does not know. Nil if there are none.
+	Attributes map[string]string
 }
@@ -5,11 +5,13 @@ import (
 	"sort"
 
 	"github.com/julnicolas/httpmon/pkg/alert"
+	"github.com/julnicolas/httpmon/pkg/backend"
 )
 
 // ActivityMonitor monitors alert states
 type ActivityMonitor struct {
 	states map[alert.State]map[alert.NameT]alert.Alert
+	muted  map[alert.NameT]string // why alerts are muted: silenced or inhibited
 }
 
 // alert_slice is a sortable alert slice. Items are sorted by Name().
@@ -22,6 +24,7 @@ func (o alert_slice) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
 func NewActivityMonitor() *ActivityMonitor {
 	o := ActivityMonitor{
 		states: make(map[alert.State]map[alert.NameT]alert.Alert),
+		muted:  make(map[alert.NameT]string),
 	}
 
 	o.states[alert.Inactive] = make(map[alert.NameT]alert.Alert)
@@ -31,7 +34,8 @@ func NewActivityMonitor() *ActivityMonitor {
 	return &o
 }
 
-func (o *ActivityMonitor) Monitor(a alert.Alert) {
+func (o *ActivityMonitor) Monitor(t backend.AlertStateTransition) {
+	a := t.Alert
 	name := a.Name()
 	current := a.State()
 	u, v := current.Others()
@@ -39,6 +43,15 @@ func (o *ActivityMonitor) Monitor(a alert.Alert) {
 	delete(o.states[u], name)
 	delete(o.states[v], name)
 	o.states[current][name] = a
+
+	switch {
+	case t.Silenced:
+		o.muted[name] = "silenced"
+	case t.Inhibited:
+		o.muted[name] = "inhibited"
+	default:
+		delete(o.muted, name)
+	}
 }
 
 // ActivityTxt returns a string describing current alert activity
@@ -61,7 +74,11 @@ func (o *ActivityMonitor) Enabled() int {
 func (o *ActivityMonitor) activitySliceTxt(header string, alerts alert_slice) string {
 	txt := header + ":\n"
 	for _, a := range alerts {
-		txt += fmt.Sprintf("  Name: %s\n  Description: %s\n\n", a.Name(), a.Description())
+		muted := ""
+		if why, ok := o.muted[a.Name()]; ok {
+			muted = " (" + why + ")"
+		}
+		txt += fmt.Sprintf("  Name: %s%s\n  Description: %s\n\n", a.Name(), muted, a.Description())
 	}
 	return txt
 }
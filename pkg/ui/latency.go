@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/julnicolas/httpmon/pkg/metrics"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+)
+
+// latencyColors are the colors of quantile series, from lowest to
+// highest quantile
+var latencyColors = []cell.Color{cell.ColorNumber(33), cell.ColorYellow, cell.ColorRed}
+
+type Latency struct {
+	names []string // series names, one per quantile
+	line  *Line
+	txt   *ListLayout
+}
+
+func NewLatency() (*Latency, error) {
+	names := make([]string, 0, len(metrics.LatencyQuantiles))
+	for _, q := range metrics.LatencyQuantiles {
+		names = append(names, quantileName(q))
+	}
+
+	// The first quantile replaces the line's placeholder series
+	line, err := NewLine(names[0])
+	if err != nil {
+		return nil, err
+	}
+
+	txt, err := NewListLayout("")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Latency{
+		names: names,
+		line:  line,
+		txt:   txt,
+	}, err
+}
+
+// quantileName returns the usual short name of a quantile e.g. p99
+func quantileName(q float64) string {
+	return fmt.Sprintf("p%g", q*100)
+}
+
+// Values plots one line per quantile series
+func (o *Latency) Values(series [][]float64) {
+	for i := 0; i < min(len(series), len(o.names)); i++ {
+		o.line.Series(o.names[i], series[i], latencyColors[i%len(latencyColors)])
+	}
+}
+
+func (o *Latency) Text(txt string) {
+	o.txt.Text(txt)
+}
+
+func (o *Latency) Layout() container.Option {
+	return container.Option(
+		container.SplitVertical(
+			container.Left(
+				o.line.Layout(),
+			),
+			container.Right(
+				o.txt.Layout(),
+			),
+			container.SplitPercent(70),
+		),
+	)
+}
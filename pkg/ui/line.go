@@ -30,6 +30,11 @@ func (o *Line) Values(values []float64) {
 	o.line.Series(o.name, values)
 }
 
+// Series plots values as an additional named line drawn in color
+func (o *Line) Series(name string, values []float64, color cell.Color) {
+	o.line.Series(name, values, linechart.SeriesCellOpts(cell.FgColor(color)))
+}
+
 // Layout returns this pages layout so that it can be rendered
 func (o *Line) Layout() container.Option {
 	return container.PlaceWidget(o.line)
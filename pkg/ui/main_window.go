@@ -13,12 +13,15 @@ type MainWindow struct {
 	activeTab        uint
 	reqsPerHost      *RequestsPerHost
 	reqsPerSec       *RequestsPerSecond
+	latency          *Latency
 	routesPerStatus  *RoutesPerStatus
 	alerts           *Alerts
 	reqsPerHostB     *button.Button
 	reqsPerSecB      *button.Button
+	latencyB         *button.Button
 	routesPerStatusB *button.Button
 	alertsB          *button.Button
+	status           string // ingestion status displayed next to the page's title
 }
 
 func (o *MainWindow) ReqsPerHost(reqList string, topk []float64) {
@@ -31,6 +34,11 @@ func (o *MainWindow) ReqsPerSec(perSectionTxt string, values []float64) {
 	o.reqsPerSec.Values(values)
 }
 
+func (o *MainWindow) Latency(perSectionTxt string, series [][]float64) {
+	o.latency.Text(perSectionTxt)
+	o.latency.Values(series)
+}
+
 func (o *MainWindow) RoutesPerStatus(routeList string, repartition StatusPercent) {
 	o.routesPerStatus.Text(routeList)
 	o.routesPerStatus.StatusPercent(repartition)
@@ -40,6 +48,16 @@ func (o *MainWindow) Alerts(txt, logs string) {
 	o.alerts.Alerts(txt, logs)
 }
 
+// Status sets the ingestion status shown next to the active page's title
+func (o *MainWindow) Status(txt string) {
+	o.status = txt
+}
+
+// alertsActive returns true if the Alerts page is displayed
+func (o *MainWindow) alertsActive() bool {
+	return o.activeTab == 4
+}
+
 func NewMainWindow() (*MainWindow, error) {
 	rPerHost, err := NewRequestsPerHost(
 		"no incomming requests",
@@ -55,6 +73,11 @@ func NewMainWindow() (*MainWindow, error) {
 		return nil, err
 	}
 
+	latency, err := NewLatency()
+	if err != nil {
+		return nil, err
+	}
+
 	status, err := NewRoutesPerStatus(
 		"no incomming requests",
 		75.0,
@@ -73,6 +96,7 @@ func NewMainWindow() (*MainWindow, error) {
 	b := &MainWindow{
 		reqsPerHost:     rPerHost,
 		reqsPerSec:      rPerS,
+		latency:         latency,
 		routesPerStatus: status,
 		alerts:          alerts,
 	}
@@ -91,10 +115,14 @@ type childPage struct {
 
 func (o *MainWindow) Layout() container.Option {
 	page := o.activePage()
+	title := page.Name
+	if o.status != "" {
+		title += " | " + o.status
+	}
 	return container.SplitHorizontal(
 		container.Top(
 			container.Border(linestyle.Light),
-			container.BorderTitle(page.Name),
+			container.BorderTitle(title),
 			page.P.Layout(),
 		),
 		container.Bottom(
@@ -121,11 +149,16 @@ func (o *MainWindow) getChildPage(id uint) childPage {
 			P:    o.reqsPerSec,
 		}
 	case 2:
+		return childPage{
+			Name: "Latency",
+			P:    o.latency,
+		}
+	case 3:
 		return childPage{
 			Name: "Status",
 			P:    o.routesPerStatus,
 		}
-	case 3:
+	case 4:
 		return childPage{
 			Name: "Alerts",
 			P:    o.alerts,
@@ -144,9 +177,13 @@ func (o *MainWindow) tabLayout() container.Option {
 		buttonLayout(
 			container.PlaceWidget(o.reqsPerSecB),
 			buttonLayout(
-				container.PlaceWidget(o.routesPerStatusB),
-				container.PlaceWidget(o.alertsB),
-				10,
+				container.PlaceWidget(o.latencyB),
+				buttonLayout(
+					container.PlaceWidget(o.routesPerStatusB),
+					container.PlaceWidget(o.alertsB),
+					10,
+				),
+				11,
 			),
 			14,
 		),
@@ -202,10 +239,18 @@ func (o *MainWindow) newButtons() error {
 	if err != nil {
 		return err
 	}
+	r5, err := button.New(o.getChildPage(4).Name, func() error {
+		o.activeTab = 4
+		return nil
+	}, opts...)
+	if err != nil {
+		return err
+	}
 
 	o.reqsPerHostB = r1
 	o.reqsPerSecB = r2
-	o.routesPerStatusB = r3
-	o.alertsB = r4
+	o.latencyB = r3
+	o.routesPerStatusB = r4
+	o.alertsB = r5
 	return err
 }
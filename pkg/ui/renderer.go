@@ -21,6 +21,14 @@ type Renderer struct {
 	cancel context.CancelFunc
 	errRun error
 	view   *View // should be the opposite -> renderer a dep
+	// alertsKeys are the actions bound to keys on the Alerts page
+	alertsKeys []keyBinding
+}
+
+type keyBinding struct {
+	key    rune
+	help   string
+	action func()
 }
 
 func NewRenderer() *Renderer {
@@ -36,6 +44,12 @@ func (o *Renderer) View() *View {
 	return o.view
 }
 
+// OnAlertsKey calls action when key is pressed on the Alerts page,
+// help is displayed on the page. It must be called before Init.
+func (o *Renderer) OnAlertsKey(key rune, help string, action func()) {
+	o.alertsKeys = append(o.alertsKeys, keyBinding{key, help, action})
+}
+
 // Init initialises the Renderer
 func (o *Renderer) Init() (err error) {
 	o.term, err = tcell.New(tcell.ColorMode(terminalapi.ColorMode256))
@@ -52,20 +66,36 @@ func (o *Renderer) Init() (err error) {
 	if err != nil {
 		return err
 	}
+	for _, b := range o.alertsKeys {
+		o.view.keys += fmt.Sprintf("%c: %s\n", b.key, b.help)
+	}
+	if o.view.keys != "" {
+		o.view.keys += "\n"
+	}
 
 	o.ctx, o.cancel = context.WithCancel(context.Background())
 	go func() {
-		o.errRun = termdash.Run(o.ctx, o.term, o.container, termdash.KeyboardSubscriber(o.quitter), termdash.RedrawInterval(16*time.Millisecond))
+		o.errRun = termdash.Run(o.ctx, o.term, o.container, termdash.KeyboardSubscriber(o.keyboard), termdash.RedrawInterval(16*time.Millisecond))
 	}()
 
 	o.running = true
 	return err
 }
 
-func (o *Renderer) quitter(k *terminalapi.Keyboard) {
+func (o *Renderer) keyboard(k *terminalapi.Keyboard) {
 	if k.Key == keyboard.KeyEsc || k.Key == keyboard.KeyCtrlC {
 		o.running = false
 		o.cancel()
+		return
+	}
+
+	if !o.view.main.alertsActive() {
+		return
+	}
+	for _, b := range o.alertsKeys {
+		if k.Key == keyboard.Key(b.key) {
+			b.action()
+		}
 	}
 }
 
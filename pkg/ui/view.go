@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"slices"
 	"sort"
-	"time"
 
 	"github.com/julnicolas/httpmon/pkg/alert"
 	"github.com/julnicolas/httpmon/pkg/backend"
@@ -15,6 +14,7 @@ import (
 type View struct {
 	main   *MainWindow
 	layout container.Option
+	keys   string // help of the Alerts page's key bindings
 }
 
 type kv struct {
@@ -87,6 +87,65 @@ func (o *View) ReqsPerSec(m metrics.CounterVector) {
 	o.main.ReqsPerSec(txt, m.Total())
 }
 
+// Latency displays latency quantiles along with the distribution of
+// response sizes
+func (o *View) Latency(m metrics.QuantileVector, b metrics.Distribution) {
+	// Sort sections in lexicographic order
+	perSection := m.TypedLabels()
+	sections := make([]string, 0, len(perSection))
+	for k := range perSection {
+		sections = append(sections, k)
+	}
+	slices.Sort(sections)
+
+	txt := "Last (seconds):\n"
+	txt += "  Total:\n" + quantilesTxt(m.Quantiles(), m.Total())
+	txt += "  Per Section:\n"
+	for _, section := range sections {
+		txt += fmt.Sprintf("    %s:\n", section)
+		txt += "  " + quantilesTxt(m.Quantiles(), perSection[section])
+	}
+
+	txt += "\nResponse size over the retained periods (bytes):\n"
+	txt += "  Total:\n" + sketchTxt(b.Quantiles(), b.Merge(0))
+	txt += "  Per Section:\n"
+	bySection := b.TypedLabels()
+	sections = sections[:0]
+	for k := range bySection {
+		sections = append(sections, k)
+	}
+	slices.Sort(sections)
+	for _, section := range sections {
+		s, _ := b.LabelMerge(section, 0)
+		txt += fmt.Sprintf("    %s:\n", section)
+		txt += "  " + sketchTxt(b.Quantiles(), s)
+	}
+
+	o.main.Latency(txt, m.Total())
+}
+
+// quantilesTxt returns the last value of every quantile series on one line
+func quantilesTxt(quantiles []float64, series [][]float64) string {
+	txt := "   "
+	for i, q := range quantiles {
+		last := 0.0
+		if i < len(series) && len(series[i]) > 0 {
+			last = series[i][len(series[i])-1]
+		}
+		txt += fmt.Sprintf(" %s: %.3f", quantileName(q), last)
+	}
+	return txt + "\n"
+}
+
+// sketchTxt returns quantiles estimated by a sketch on one line
+func sketchTxt(quantiles []float64, s *metrics.Sketch) string {
+	txt := "   "
+	for _, q := range quantiles {
+		txt += fmt.Sprintf(" %s: %.0f", quantileName(q), s.Quantile(q))
+	}
+	return txt + "\n"
+}
+
 func (o *View) RoutesPerStatus(m metrics.RoutePerStatusCounter) {
 	// Data structures to be sorted to display the request listing
 	sortedStatuses := make([]metrics.StatusCodeT, 0, len(m.TypedLabels()))
@@ -168,80 +227,62 @@ func (o *View) RoutesPerStatus(m metrics.RoutePerStatusCounter) {
 	o.main.RoutesPerStatus(txt, status)
 }
 
-type alertLog struct {
-	Date  time.Time
-	Name  alert.NameT
-	State alert.State
+// Ingestion displays the parser in use, the number of
+// lines which could not be parsed and the number of traces dropped
+// because they were too late to be reordered
+func (o *View) Ingestion(parser string, parseErrors uint64, dropped uint64) {
+	if parser == "" {
+		parser = "detecting..."
+	}
+	o.main.Status(fmt.Sprintf("parser: %s, parse errors: %d, late traces dropped: %d", parser, parseErrors, dropped))
 }
 
-// TODO: rm global variable
-type alertLogSlice []alertLog
+// alertLogSize is the number of state changes shown on the Alerts page
+const alertLogSize = 100
 
-func (o alertLogSlice) Len() int           { return len(o) }
-func (o alertLogSlice) Less(i, j int) bool { return o[i].Date.Before(o[j].Date) }
-func (o alertLogSlice) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
-
-var gAlertLogs alertLogSlice
+// TODO: rm global variable
 var gActivityMonitor ActivityMonitor = *NewActivityMonitor()
 
-func (o *View) Alerts(alerts <-chan backend.AlertStateTransition) {
-	select {
-	case a := <-alerts:
-		switch a.Alert.State() {
-		case alert.Inactive:
-			gActivityMonitor.Monitor(a.Alert)
-
-			// logs
-			if a.Prev == alert.Active {
-				gAlertLogs = append(
-					gAlertLogs,
-					alertLog{
-						Date:  time.Unix(a.Time, 0),
-						Name:  a.Alert.Name(),
-						State: alert.Inactive,
-					})
-			}
-		case alert.Pending:
-			gActivityMonitor.Monitor(a.Alert)
-		case alert.Active:
-			gActivityMonitor.Monitor(a.Alert)
-
-			// logs
-			gAlertLogs = append(
-				gAlertLogs,
-				alertLog{
-					Date:  time.Unix(a.Time, 0),
-					Name:  a.Alert.Name(),
-					State: alert.Active,
-				})
+// Alerts displays alert states and the latest state changes of history,
+// on alert state transitions. Every pending transition is read.
+func (o *View) Alerts(alerts <-chan backend.AlertStateTransition, history func(backend.HistoryQuery) []backend.HistoryEntry) {
+	read := false
+	for drained := false; !drained; {
+		select {
+		case a := <-alerts:
+			gActivityMonitor.Monitor(a)
+			read = true
 		default:
-			// critical logical error because enums do not exist
-			err := fmt.Errorf("unsupported alert state %v", a)
-			panic(err)
+			drained = true
 		}
-	default:
+	}
+	if !read {
 		// Nothing to read let's try some other time
 		return
 	}
 
 	logs := "Log:\n"
-	for _, l := range gAlertLogs {
-		logs += "  " + alertLogActivityTxt(l)
+	q := backend.HistoryQuery{States: []alert.State{alert.Active, alert.Inactive}, Limit: alertLogSize}
+	for _, e := range history(q) {
+		// Only resolved alerts are logged as inactive, not pending ones
+		if e.State == alert.Inactive && e.Prev != alert.Active {
+			continue
+		}
+		logs += "  " + alertLogActivityTxt(e)
 	}
 
-	o.main.Alerts(gActivityMonitor.ActivityTxt(), logs)
+	o.main.Alerts(o.keys+gActivityMonitor.ActivityTxt(), logs)
 }
 
-func alertLogActivityTxt(log alertLog) string {
-	switch log.State {
-	case alert.Active:
-		return fmt.Sprintf("%s\n    State: Active\n    Time: %s\n", log.Name, log.Date)
-	case alert.Inactive:
-		return fmt.Sprintf("%s\n    State: Inactive\n    Time: %s\n", log.Name, log.Date)
-	default:
-		// critical logical bug if that happens
-		panic("pending alert or unsupported type has been placed in alert log slice")
+func alertLogActivityTxt(e backend.HistoryEntry) string {
+	muted := ""
+	switch {
+	case e.Silenced:
+		muted = " (silenced)"
+	case e.Inhibited:
+		muted = " (inhibited)"
 	}
+	return fmt.Sprintf("%s\n    State: %s%s\n    Time: %s\n", e.Alert, e.State, muted, e.Time.Local())
 }
 
 func NewView() (*View, error) {